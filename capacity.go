@@ -0,0 +1,117 @@
+package dynamorm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ConsumedCapacity aggregates the capacity units consumed by DynamoDB operations,
+// as reported when WithConsumedCapacity is enabled. It is safe for concurrent use.
+// The zero value is ready to use.
+type ConsumedCapacity struct {
+	mu    sync.Mutex
+	total types.ConsumedCapacity
+}
+
+// Add accumulates the given consumed capacities.
+func (c *ConsumedCapacity) Add(caps ...types.ConsumedCapacity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cc := range caps {
+		addConsumedCapacity(&c.total, cc)
+	}
+}
+
+// Total returns a snapshot of the accumulated capacity units, broken down by table and
+// secondary indexes when the INDEXES level is requested. TableName is left unset.
+func (c *ConsumedCapacity) Total() types.ConsumedCapacity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total types.ConsumedCapacity
+	addConsumedCapacity(&total, c.total)
+	return total
+}
+
+// Reset clears the accumulated capacity units.
+func (c *ConsumedCapacity) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.total = types.ConsumedCapacity{}
+}
+
+type capacityKey struct{}
+
+// WithCapacity returns a copy of ctx that makes every storage operation using it also add its
+// consumed capacity to c, in addition to the aggregate counter of the storage. It allows
+// attributing capacity to a request, an endpoint or a single write.
+func WithCapacity(ctx context.Context, c *ConsumedCapacity) context.Context {
+	return context.WithValue(ctx, capacityKey{}, c)
+}
+
+// recordCapacity adds the capacity consumed by an operation to the aggregate counter
+// and to the ConsumedCapacity attached to ctx, if any.
+func recordCapacity(ctx context.Context, total *ConsumedCapacity, caps ...types.ConsumedCapacity) {
+	if len(caps) == 0 {
+		return
+	}
+	if total != nil {
+		total.Add(caps...)
+	}
+	if c, ok := ctx.Value(capacityKey{}).(*ConsumedCapacity); ok && c != nil {
+		c.Add(caps...)
+	}
+}
+
+// capacities converts the single consumed capacity returned by most operations.
+func capacities(cc *types.ConsumedCapacity) []types.ConsumedCapacity {
+	if cc == nil {
+		return nil
+	}
+	return []types.ConsumedCapacity{*cc}
+}
+
+func addConsumedCapacity(dst *types.ConsumedCapacity, src types.ConsumedCapacity) {
+	dst.CapacityUnits = addUnits(dst.CapacityUnits, src.CapacityUnits)
+	dst.ReadCapacityUnits = addUnits(dst.ReadCapacityUnits, src.ReadCapacityUnits)
+	dst.WriteCapacityUnits = addUnits(dst.WriteCapacityUnits, src.WriteCapacityUnits)
+
+	if src.Table != nil {
+		if dst.Table == nil {
+			dst.Table = &types.Capacity{}
+		}
+		addCapacity(dst.Table, *src.Table)
+	}
+	dst.GlobalSecondaryIndexes = addIndexCapacity(dst.GlobalSecondaryIndexes, src.GlobalSecondaryIndexes)
+	dst.LocalSecondaryIndexes = addIndexCapacity(dst.LocalSecondaryIndexes, src.LocalSecondaryIndexes)
+}
+
+func addIndexCapacity(dst, src map[string]types.Capacity) map[string]types.Capacity {
+	for name, c := range src {
+		if dst == nil {
+			dst = make(map[string]types.Capacity, len(src))
+		}
+		v := dst[name]
+		addCapacity(&v, c)
+		dst[name] = v
+	}
+	return dst
+}
+
+func addCapacity(dst *types.Capacity, src types.Capacity) {
+	dst.CapacityUnits = addUnits(dst.CapacityUnits, src.CapacityUnits)
+	dst.ReadCapacityUnits = addUnits(dst.ReadCapacityUnits, src.ReadCapacityUnits)
+	dst.WriteCapacityUnits = addUnits(dst.WriteCapacityUnits, src.WriteCapacityUnits)
+}
+
+func addUnits(a, b *float64) *float64 {
+	if b == nil {
+		return a
+	}
+	return aws.Float64(aws.ToFloat64(a) + *b)
+}
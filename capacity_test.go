@@ -0,0 +1,88 @@
+package dynamorm_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/require"
+	"github.com/vpriem/dynamorm"
+)
+
+func TestConsumedCapacity(t *testing.T) {
+	t.Run("should accumulate capacity", func(t *testing.T) {
+		c := &dynamorm.ConsumedCapacity{}
+		require.Equal(t, types.ConsumedCapacity{}, c.Total())
+
+		c.Add(
+			types.ConsumedCapacity{
+				TableName:         aws.String("TestTable"),
+				CapacityUnits:     aws.Float64(1.5),
+				ReadCapacityUnits: aws.Float64(1.5),
+				Table:             &types.Capacity{CapacityUnits: aws.Float64(1)},
+				GlobalSecondaryIndexes: map[string]types.Capacity{
+					"GSI1": {CapacityUnits: aws.Float64(0.5)},
+				},
+			},
+			types.ConsumedCapacity{
+				CapacityUnits:      aws.Float64(2),
+				WriteCapacityUnits: aws.Float64(2),
+				Table:              &types.Capacity{CapacityUnits: aws.Float64(1)},
+				GlobalSecondaryIndexes: map[string]types.Capacity{
+					"GSI1": {CapacityUnits: aws.Float64(0.5)},
+					"GSI2": {CapacityUnits: aws.Float64(0.5)},
+				},
+				LocalSecondaryIndexes: map[string]types.Capacity{
+					"LSI1": {WriteCapacityUnits: aws.Float64(1)},
+				},
+			},
+		)
+
+		require.Equal(t, types.ConsumedCapacity{
+			CapacityUnits:      aws.Float64(3.5),
+			ReadCapacityUnits:  aws.Float64(1.5),
+			WriteCapacityUnits: aws.Float64(2),
+			Table:              &types.Capacity{CapacityUnits: aws.Float64(2)},
+			GlobalSecondaryIndexes: map[string]types.Capacity{
+				"GSI1": {CapacityUnits: aws.Float64(1)},
+				"GSI2": {CapacityUnits: aws.Float64(0.5)},
+			},
+			LocalSecondaryIndexes: map[string]types.Capacity{
+				"LSI1": {WriteCapacityUnits: aws.Float64(1)},
+			},
+		}, c.Total())
+
+		c.Reset()
+		require.Equal(t, types.ConsumedCapacity{}, c.Total())
+	})
+
+	t.Run("should return a snapshot", func(t *testing.T) {
+		c := &dynamorm.ConsumedCapacity{}
+		c.Add(types.ConsumedCapacity{Table: &types.Capacity{CapacityUnits: aws.Float64(1)}})
+
+		total := c.Total()
+		*total.Table.CapacityUnits = 10
+		require.Equal(t, 1.0, *c.Total().Table.CapacityUnits)
+	})
+
+	t.Run("should be safe for concurrent use", func(t *testing.T) {
+		c := &dynamorm.ConsumedCapacity{}
+		ctx := dynamorm.WithCapacity(context.TODO(), c)
+		require.NotNil(t, ctx)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.Add(types.ConsumedCapacity{CapacityUnits: aws.Float64(1)})
+				_ = c.Total()
+			}()
+		}
+		wg.Wait()
+
+		require.Equal(t, 10.0, *c.Total().CapacityUnits)
+	})
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const dynamormPath = "github.com/vpriem/dynamorm"
+
+// keyOptions are the options of the dynamorm tag declaring key templates, as understood by dynamorm.Keyed.
+var keyOptions = []string{"pk", "sk", "gsi1pk", "gsi1sk", "gsi2pk", "gsi2sk"}
+
+// indexes maps the index of a query directive to its key options and Storage method.
+var indexes = map[string]struct{ name, pk, sk, method string }{
+	"table": {"the table", "pk", "sk", "Query"},
+	"gsi1":  {"GSI1", "gsi1pk", "gsi1sk", "QueryGSI1"},
+	"gsi2":  {"GSI2", "gsi2pk", "gsi2sk", "QueryGSI2"},
+}
+
+// keyPart is either literal text, or a placeholder when field is set.
+type keyPart struct {
+	text  string
+	field string
+}
+
+type keyTemplate []keyPart
+
+// fields returns the distinct fields of the template, in order of appearance.
+func (t keyTemplate) fields() []string {
+	var names []string
+	for _, part := range t {
+		if part.field != "" && !slices.Contains(names, part.field) {
+			names = append(names, part.field)
+		}
+	}
+	return names
+}
+
+// prefix returns the literal text before the first placeholder, and whether the template is literal only.
+func (t keyTemplate) prefix() (string, bool) {
+	var b strings.Builder
+	for _, part := range t {
+		if part.field != "" {
+			return b.String(), false
+		}
+		b.WriteString(part.text)
+	}
+	return b.String(), true
+}
+
+// query is a //dynamorm:query directive.
+type query struct {
+	name  string
+	index string
+}
+
+// entity is a struct declaring key templates.
+type entity struct {
+	name       string
+	fields     map[string]ast.Expr
+	keys       map[string]keyTemplate
+	queries    []query
+	beforeSave bool
+}
+
+// generator holds the state of the generation of a file.
+type generator struct {
+	fset     *token.FileSet
+	file     *ast.File
+	basics   map[string]string          // Local type names with a basic underlying type
+	methods  map[string]map[string]bool // Methods of the local types
+	imports  map[string]bool
+	buf      bytes.Buffer
+	entities []*entity
+}
+
+// generate parses a Go file and returns the generated source for the given structs,
+// or for all the structs declaring keys when names is empty.
+func generate(filename string, names []string) ([]byte, error) {
+	g := &generator{
+		fset:    token.NewFileSet(),
+		basics:  make(map[string]string),
+		imports: make(map[string]bool),
+	}
+
+	file, err := parser.ParseFile(g.fset, filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	g.file = file
+
+	if err = g.parsePackage(filename); err != nil {
+		return nil, err
+	}
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || (len(names) > 0 && !slices.Contains(names, ts.Name.Name)) {
+				continue
+			}
+
+			doc := ts.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				doc = gen.Doc
+			}
+			e, err := g.parseEntity(ts.Name.Name, st, doc)
+			if err != nil {
+				return nil, err
+			}
+			if e == nil {
+				continue
+			}
+			e.beforeSave = g.methods[ts.Name.Name]["BeforeSave"]
+			g.entities = append(g.entities, e)
+		}
+	}
+
+	for _, name := range names {
+		if !slices.ContainsFunc(g.entities, func(e *entity) bool { return e.name == name }) {
+			return nil, fmt.Errorf("struct %s not found or declaring no keys", name)
+		}
+	}
+	if len(g.entities) == 0 {
+		return nil, fmt.Errorf("no struct declaring keys in %s", filename)
+	}
+
+	return g.render()
+}
+
+// parsePackage parses the files of the package of the file, collecting the local types
+// with a basic underlying type and the methods of the local types.
+func (g *generator) parsePackage(filename string) error {
+	dir := filepath.Dir(filename)
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return err
+	}
+
+	g.methods = make(map[string]map[string]bool)
+	for _, p := range paths {
+		var file *ast.File
+		if filepath.Base(p) == filepath.Base(filename) {
+			file = g.file
+		} else if strings.HasSuffix(p, "_dynamorm.go") || strings.HasSuffix(p, "_dynamorm_test.go") {
+			continue
+		} else if file, err = parser.ParseFile(token.NewFileSet(), p, nil, 0); err != nil {
+			return err
+		}
+		if file.Name.Name != g.file.Name.Name {
+			continue
+		}
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						if ident, ok := ts.Type.(*ast.Ident); ok && basicKind(ident.Name) != "" {
+							g.basics[ts.Name.Name] = ident.Name
+						}
+					}
+				}
+			case *ast.FuncDecl:
+				if recv := receiverName(decl); recv != "" {
+					if g.methods[recv] == nil {
+						g.methods[recv] = make(map[string]bool)
+					}
+					g.methods[recv][decl.Name.Name] = true
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// receiverName returns the type name of the receiver of a method, or an empty string.
+func receiverName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	t := fn.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if ident, ok := t.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// parseEntity parses the key templates and the query directives of a struct,
+// returning nil if it declares no keys.
+func (g *generator) parseEntity(name string, st *ast.StructType, doc *ast.CommentGroup) (*entity, error) {
+	e := &entity{
+		name:   name,
+		fields: make(map[string]ast.Expr),
+		keys:   make(map[string]keyTemplate),
+	}
+
+	var tags []string
+	for _, field := range st.Fields.List {
+		for _, ident := range field.Names {
+			e.fields[ident.Name] = field.Type
+		}
+		if field.Tag != nil {
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+			if value, ok := reflect.StructTag(tag).Lookup("dynamorm"); ok {
+				tags = append(tags, value)
+			}
+		}
+	}
+
+	for _, tag := range tags {
+		for _, option := range strings.Split(tag, ",") {
+			key, template, _ := strings.Cut(strings.TrimSpace(option), "=")
+			if !slices.Contains(keyOptions, key) {
+				continue
+			}
+			if _, ok := e.keys[key]; ok {
+				return nil, fmt.Errorf("%s: duplicate %s", name, key)
+			}
+			parts, err := g.parseTemplate(e, template)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", name, key, err)
+			}
+			e.keys[key] = parts
+		}
+	}
+	if len(e.keys) == 0 {
+		return nil, nil
+	}
+	if e.keys["pk"] == nil {
+		return nil, fmt.Errorf("%s: missing pk", name)
+	}
+
+	if doc != nil {
+		for _, c := range doc.List {
+			directive, ok := strings.CutPrefix(c.Text, "//dynamorm:query")
+			if !ok {
+				continue
+			}
+			args := strings.Fields(directive)
+			if len(args) != 2 {
+				return nil, fmt.Errorf("%s: expected //dynamorm:query <Name> <table|gsi1|gsi2>, got %q", name, c.Text)
+			}
+			index, ok := indexes[args[1]]
+			if !ok {
+				return nil, fmt.Errorf("%s: unknown index %q", name, args[1])
+			}
+			if e.keys[index.pk] == nil {
+				return nil, fmt.Errorf("%s: query %s: missing %s", name, args[0], index.pk)
+			}
+			e.queries = append(e.queries, query{args[0], args[1]})
+		}
+	}
+
+	return e, nil
+}
+
+// parseTemplate parses a key template of an entity, e.g. USER#{ID}.
+func (g *generator) parseTemplate(e *entity, template string) (keyTemplate, error) {
+	var parts keyTemplate
+	for template != "" {
+		start := strings.IndexAny(template, "{}")
+		if start < 0 {
+			parts = append(parts, keyPart{text: template})
+			break
+		}
+		if template[start] == '}' {
+			return nil, fmt.Errorf("unexpected } in %q", template)
+		}
+		if start > 0 {
+			parts = append(parts, keyPart{text: template[:start]})
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed { in %q", template)
+		}
+		name := template[start+1 : start+end]
+		typ, ok := e.fields[name]
+		if !ok || !token.IsExported(name) {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		if !g.keyable(typ) {
+			return nil, fmt.Errorf("field %q of type %s cannot be used in a key", name, g.typeString(typ))
+		}
+		parts = append(parts, keyPart{field: name})
+
+		template = template[start+end+1:]
+	}
+	return parts, nil
+}
+
+// render generates the source of the file.
+func (g *generator) render() ([]byte, error) {
+	for _, e := range g.entities {
+		g.renderEntity(e)
+	}
+	body := g.buf.Bytes()
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by dynamorm-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", g.file.Name.Name)
+
+	// Standard library imports come first, separated from the others.
+	var std, others []string
+	for p := range g.imports {
+		if strings.Contains(strings.SplitN(p, "/", 2)[0], ".") {
+			others = append(others, p)
+		} else {
+			std = append(std, p)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(others)
+	if len(std)+len(others) > 0 {
+		fmt.Fprintf(&out, "import (\n")
+		for _, p := range std {
+			fmt.Fprintf(&out, "\t%s\n", p)
+		}
+		if len(std) > 0 && len(others) > 0 {
+			fmt.Fprintf(&out, "\n")
+		}
+		for _, p := range others {
+			fmt.Fprintf(&out, "\t%s\n", p)
+		}
+		fmt.Fprintf(&out, ")\n")
+	}
+	out.Write(body)
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated source: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+// renderEntity generates the key constructors, methods and query helpers of an entity.
+func (g *generator) renderEntity(e *entity) {
+	for _, key := range keyOptions {
+		if t, ok := e.keys[key]; ok {
+			g.renderConstructor(e, key, t)
+		}
+	}
+
+	recv := strings.ToLower(e.name[:1])
+	for _, method := range []struct{ name, pk, sk string }{
+		{"PkSk", "pk", "sk"},
+		{"GSI1", "gsi1pk", "gsi1sk"},
+		{"GSI2", "gsi2pk", "gsi2sk"},
+	} {
+		fmt.Fprintf(&g.buf, "\n// %s implements dynamorm.Entity.\n", method.name)
+		fmt.Fprintf(&g.buf, "func (%s *%s) %s() (string, string) {\n", recv, e.name, method.name)
+		fmt.Fprintf(&g.buf, "\treturn %s, %s\n}\n", g.keyCall(e, recv, method.pk), g.keyCall(e, recv, method.sk))
+	}
+
+	if !e.beforeSave {
+		fmt.Fprintf(&g.buf, "\n// BeforeSave implements dynamorm.Entity.\n")
+		fmt.Fprintf(&g.buf, "func (%s *%s) BeforeSave() error {\n\treturn nil\n}\n", recv, e.name)
+	}
+
+	for _, q := range e.queries {
+		g.renderQuery(e, q)
+	}
+}
+
+// constructorName returns the name of the key constructor of an entity, e.g. OrderGSI1PK.
+func constructorName(e *entity, key string) string {
+	return e.name + strings.ToUpper(key)
+}
+
+// keyCall returns the call of the key constructor of an entity by a method, or an empty string literal.
+func (g *generator) keyCall(e *entity, recv, key string) string {
+	t, ok := e.keys[key]
+	if !ok {
+		return `""`
+	}
+	args := make([]string, 0)
+	for _, field := range t.fields() {
+		args = append(args, recv+"."+field)
+	}
+	return constructorName(e, key) + "(" + strings.Join(args, ", ") + ")"
+}
+
+// params returns the parameters of the fields of a template.
+func (g *generator) params(e *entity, t keyTemplate) ([]string, map[string]string) {
+	var params []string
+	names := make(map[string]string)
+	for _, field := range t.fields() {
+		names[field] = paramName(field)
+		params = append(params, names[field]+" "+g.typeString(e.fields[field]))
+	}
+	return params, names
+}
+
+// renderConstructor generates the key constructor of an entity.
+func (g *generator) renderConstructor(e *entity, key string, t keyTemplate) {
+	params, names := g.params(e, t)
+
+	fmt.Fprintf(&g.buf, "\n// %s returns the %s key of %s entities: %s.\n", constructorName(e, key), strings.ToUpper(key), e.name, templateString(t))
+	fmt.Fprintf(&g.buf, "func %s(%s) string {\n", constructorName(e, key), strings.Join(params, ", "))
+
+	var concat []string
+	values := make(map[string]string)
+	for i, field := range t.fields() {
+		v, typ := names[field], e.fields[field]
+		fmt.Fprintf(&g.buf, "\tif %s == %s {\n\t\treturn \"\"\n\t}\n", v, g.zeroExpr(typ))
+		if expr := g.formatExpr(v, typ); expr != v {
+			v = "v" + strconv.Itoa(i)
+			fmt.Fprintf(&g.buf, "\t%s := %s\n", v, expr)
+			if g.kind(typ) == "" {
+				// String() may still return an empty string.
+				fmt.Fprintf(&g.buf, "\tif %s == \"\" {\n\t\treturn \"\"\n\t}\n", v)
+			}
+		}
+		values[field] = v
+	}
+	for _, part := range t {
+		if part.field != "" {
+			concat = append(concat, values[part.field])
+		} else {
+			concat = append(concat, strconv.Quote(part.text))
+		}
+	}
+	if len(concat) == 0 {
+		concat = append(concat, `""`)
+	}
+	fmt.Fprintf(&g.buf, "\treturn %s\n}\n", strings.Join(concat, " + "))
+}
+
+// renderQuery generates the query helper of a //dynamorm:query directive.
+func (g *generator) renderQuery(e *entity, q query) {
+	index := indexes[q.index]
+	pk := e.keys[index.pk]
+	params, names := g.params(e, pk)
+
+	args := make([]string, 0)
+	for _, field := range pk.fields() {
+		args = append(args, names[field])
+	}
+
+	cond := "nil"
+	if sk, ok := e.keys[index.sk]; ok {
+		switch prefix, literal := sk.prefix(); {
+		case literal:
+			cond = fmt.Sprintf("dynamorm.SkEQ(%s)", strconv.Quote(prefix))
+		case prefix != "":
+			cond = fmt.Sprintf("dynamorm.SkBeginsWith(%s)", strconv.Quote(prefix))
+		}
+	}
+
+	g.imports[`"context"`] = true
+	g.imports[strconv.Quote(dynamormPath)] = true
+
+	fmt.Fprintf(&g.buf, "\n// Query%s queries the %s entities of %s by their %s key: %s.\n", q.name, e.name, index.name, strings.ToUpper(index.pk), templateString(pk))
+	fmt.Fprintf(&g.buf, "func Query%s(ctx context.Context, storage dynamorm.StorageInterface, %s opts ...dynamorm.QueryOption) (dynamorm.QueryInterface, error) {\n",
+		q.name, strings.Join(append(params, ""), ", "))
+	fmt.Fprintf(&g.buf, "\treturn storage.%s(ctx, %s(%s), %s, opts...)\n}\n", index.method, constructorName(e, index.pk), strings.Join(args, ", "), cond)
+}
+
+// templateString returns the source of a key template.
+func templateString(t keyTemplate) string {
+	var b strings.Builder
+	for _, part := range t {
+		if part.field != "" {
+			b.WriteString("{" + part.field + "}")
+		} else {
+			b.WriteString(part.text)
+		}
+	}
+	return b.String()
+}
+
+// typeString returns the source of a type, recording the imports it needs.
+func (g *generator) typeString(expr ast.Expr) string {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if pkg, ok := sel.X.(*ast.Ident); ok {
+				g.importPackage(pkg.Name)
+			}
+		}
+		return true
+	})
+
+	var b strings.Builder
+	_ = printer.Fprint(&b, g.fset, expr)
+	return b.String()
+}
+
+// importPackage records the import of the file declaring the given package name.
+func (g *generator) importPackage(name string) {
+	for _, spec := range g.file.Imports {
+		p, _ := strconv.Unquote(spec.Path.Value)
+		if spec.Name != nil && spec.Name.Name == name {
+			g.imports[spec.Name.Name+" "+spec.Path.Value] = true
+			return
+		}
+		if spec.Name == nil && path.Base(p) == name {
+			g.imports[spec.Path.Value] = true
+			return
+		}
+	}
+}
+
+// kind returns the basic kind a value of the given type is formatted as, or an empty string
+// for the types formatted with String(). Local types implementing fmt.Stringer are formatted
+// with String(), like other non-basic types.
+func (g *generator) kind(expr ast.Expr) string {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || g.methods[ident.Name]["String"] {
+		return ""
+	}
+	if u, ok := g.basics[ident.Name]; ok {
+		return basicKind(u)
+	}
+	return basicKind(ident.Name)
+}
+
+// keyable reports whether a field type can be used in a key placeholder, following the rules
+// of dynamorm.Keyed: strings, integers, and types implementing fmt.Stringer. Types of other
+// packages are expected to implement fmt.Stringer, as their methods are not known.
+func (g *generator) keyable(expr ast.Expr) bool {
+	star, pointer := expr.(*ast.StarExpr)
+	if pointer {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return g.methods[t.Name]["String"] || (!pointer && g.kind(t) != "")
+	case *ast.SelectorExpr:
+		return true
+	default:
+		return false
+	}
+}
+
+// zeroExpr returns the expression of the zero value of the given type, so that keys
+// with a zero field are left empty, like with dynamorm.Keyed.
+func (g *generator) zeroExpr(expr ast.Expr) string {
+	if _, ok := expr.(*ast.StarExpr); ok {
+		return "nil"
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		underlying := ident.Name
+		if u, ok := g.basics[underlying]; ok {
+			underlying = u
+		}
+		switch basicKind(underlying) {
+		case "string":
+			return `""`
+		case "int", "uint":
+			return "0"
+		}
+	}
+	return "*new(" + g.typeString(expr) + ")"
+}
+
+// formatExpr returns the expression formatting a value of the given type as a string.
+func (g *generator) formatExpr(v string, expr ast.Expr) string {
+	switch g.kind(expr) {
+	case "string":
+		if ident := expr.(*ast.Ident); ident.Name != "string" {
+			return "string(" + v + ")"
+		}
+		return v
+	case "int":
+		g.imports[`"strconv"`] = true
+		return "strconv.FormatInt(int64(" + v + "), 10)"
+	case "uint":
+		g.imports[`"strconv"`] = true
+		return "strconv.FormatUint(uint64(" + v + "), 10)"
+	default:
+		return v + ".String()"
+	}
+}
+
+// basicKind returns string, int or uint for the basic types supported in keys, or an empty string.
+func basicKind(name string) string {
+	switch name {
+	case "string":
+		return "string"
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		return "int"
+	case "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+		return "uint"
+	default:
+		return ""
+	}
+}
+
+// paramName returns the parameter name of a field, e.g. customerID for CustomerID.
+func paramName(field string) string {
+	runes := []rune(field)
+	upper := 0
+	for upper < len(runes) && unicode.IsUpper(runes[upper]) {
+		upper++
+	}
+	if upper > 1 && upper < len(runes) {
+		upper--
+	}
+	for i := 0; i < upper; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+
+	name := string(runes)
+	if token.IsKeyword(name) || slices.Contains([]string{"ctx", "storage", "opts", "dynamorm", "context", "strconv"}, name) {
+		name += "_"
+	}
+	return name
+}
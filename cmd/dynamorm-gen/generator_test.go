@@ -0,0 +1,193 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeSource(t *testing.T, name, src string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(file, []byte(src), 0o644))
+	return file
+}
+
+func TestGenerate(t *testing.T) {
+	file := writeSource(t, "user.go", `package model
+
+import (
+	"time"
+
+	guuid "github.com/google/uuid"
+)
+
+type Status string
+
+type Kind int
+
+func (k Kind) String() string { return "kind" }
+
+//dynamorm:query UsersByStatus gsi1
+//dynamorm:query UserProfile table
+type User struct {
+	_       struct{} `+"`"+`dynamorm:"pk=USER#{ID},sk=PROFILE,gsi1pk=STATUS#{Status},gsi1sk=AGE#{Age}#{ID}"`+"`"+`
+	ID      guuid.UUID
+	Status  Status
+	Age     uint8
+	Created time.Time
+}
+
+func (u *User) BeforeSave() error { return nil }
+
+//dynamorm:query EventsByStream table
+type Event struct {
+	_      struct{} `+"`"+`dynamorm:"pk=STREAM#{Stream},sk={Seq},gsi2pk={Kind},gsi2sk={Type}"`+"`"+`
+	Stream string
+	Seq    int64
+	Kind   Kind
+	Type   string
+}
+
+type Ref struct{ ID string }
+
+func (r *Ref) String() string { return r.ID }
+
+type Link struct {
+	_   struct{} `+"`"+`dynamorm:"pk=LINK#{Ref}"`+"`"+`
+	Ref *Ref
+}
+
+type Plain struct {
+	ID string
+}
+`)
+
+	src, err := generate(file, nil)
+	require.NoError(t, err)
+	_, err = parser.ParseFile(token.NewFileSet(), "", src, 0)
+	require.NoError(t, err)
+
+	out := string(src)
+	require.Contains(t, out, "// Code generated by dynamorm-gen. DO NOT EDIT.\n\npackage model\n")
+	require.Contains(t, out, "import (\n\t\"context\"\n\t\"strconv\"\n\n\tguuid \"github.com/google/uuid\"\n\t\"github.com/vpriem/dynamorm\"\n)")
+
+	require.Contains(t, out, `func UserPK(id guuid.UUID) string {
+	if id == *new(guuid.UUID) {
+		return ""
+	}
+	v0 := id.String()
+	if v0 == "" {
+		return ""
+	}
+	return "USER#" + v0
+}`)
+	require.Contains(t, out, `func UserGSI1PK(status Status) string {
+	if status == "" {
+		return ""
+	}
+	v0 := string(status)
+	return "STATUS#" + v0
+}`)
+	require.Contains(t, out, `func UserGSI1SK(age uint8, id guuid.UUID) string {
+	if age == 0 {
+		return ""
+	}
+	v0 := strconv.FormatUint(uint64(age), 10)`)
+	require.Contains(t, out, `return "AGE#" + v0 + "#" + v1`)
+	require.Contains(t, out, `func (u *User) PkSk() (string, string) {
+	return UserPK(u.ID), UserSK()
+}`)
+	require.Contains(t, out, `func (u *User) GSI1() (string, string) {
+	return UserGSI1PK(u.Status), UserGSI1SK(u.Age, u.ID)
+}`)
+	require.Contains(t, out, `func (u *User) GSI2() (string, string) {
+	return "", ""
+}`)
+	require.NotContains(t, out, "func (u *User) BeforeSave() error")
+	require.Contains(t, out, `func QueryUsersByStatus(ctx context.Context, storage dynamorm.StorageInterface, status Status, opts ...dynamorm.QueryOption) (dynamorm.QueryInterface, error) {
+	return storage.QueryGSI1(ctx, UserGSI1PK(status), dynamorm.SkBeginsWith("AGE#"), opts...)
+}`)
+	require.Contains(t, out, `return storage.Query(ctx, UserPK(id), dynamorm.SkEQ("PROFILE"), opts...)`)
+
+	require.Contains(t, out, `func EventSK(seq int64) string {
+	if seq == 0 {
+		return ""
+	}
+	v0 := strconv.FormatInt(int64(seq), 10)`)
+	require.Contains(t, out, `func EventGSI2PK(kind Kind) string {
+	if kind == 0 {
+		return ""
+	}
+	v0 := kind.String()
+	if v0 == "" {
+		return ""
+	}`)
+	require.Contains(t, out, `func (e *Event) BeforeSave() error {
+	return nil
+}`)
+	require.Contains(t, out, `return storage.Query(ctx, EventPK(stream), nil, opts...)`)
+
+	require.Contains(t, out, `func LinkPK(ref *Ref) string {
+	if ref == nil {
+		return ""
+	}
+	v0 := ref.String()`)
+	require.NotContains(t, out, "Plain")
+}
+
+func TestGenerateTypes(t *testing.T) {
+	file := writeSource(t, "entities.go", `package model
+
+type A struct {
+	_  struct{} `+"`"+`dynamorm:"pk=A#{ID}"`+"`"+`
+	ID string
+}
+
+type B struct {
+	_  struct{} `+"`"+`dynamorm:"pk=B#{ID}"`+"`"+`
+	ID string
+}
+`)
+
+	src, err := generate(file, []string{"B"})
+	require.NoError(t, err)
+	require.Contains(t, string(src), "func BPK(id string) string")
+	require.NotContains(t, string(src), "func APK")
+
+	_, err = generate(file, []string{"C"})
+	require.ErrorContains(t, err, "struct C not found")
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for name, src := range map[string]string{
+		"no keys":        "type A struct{ ID string }",
+		"missing pk":     "type A struct {\n_ struct{} `dynamorm:\"sk=A\"`\n}",
+		"duplicate":      "type A struct {\n_ struct{} `dynamorm:\"pk=A,pk=B\"`\n}",
+		"unknown field":  "type A struct {\n_ struct{} `dynamorm:\"pk=A#{ID}\"`\n}",
+		"unclosed":       "type A struct {\n_ struct{} `dynamorm:\"pk=A#{ID\"`\nID string\n}",
+		"unexpected":     "type A struct {\n_ struct{} `dynamorm:\"pk=A#ID}\"`\nID string\n}",
+		"bad directive":  "//dynamorm:query A\ntype A struct {\n_ struct{} `dynamorm:\"pk=A\"`\n}",
+		"unknown index":  "//dynamorm:query A gsi3\ntype A struct {\n_ struct{} `dynamorm:\"pk=A\"`\n}",
+		"missing gsi1pk": "//dynamorm:query A gsi1\ntype A struct {\n_ struct{} `dynamorm:\"pk=A\"`\n}",
+		"unexported":     "type A struct {\n_ struct{} `dynamorm:\"pk=A#{id}\"`\nid string\n}",
+		"pointer":        "type A struct {\n_ struct{} `dynamorm:\"pk=A#{ID}\"`\nID *string\n}",
+		"struct":         "type A struct {\n_ struct{} `dynamorm:\"pk=A#{ID}\"`\nID struct{ V string }\n}",
+		"local struct":   "type B struct{}\ntype A struct {\n_ struct{} `dynamorm:\"pk=A#{ID}\"`\nID B\n}",
+		"bool":           "type A struct {\n_ struct{} `dynamorm:\"pk=A#{OK}\"`\nOK bool\n}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := generate(writeSource(t, "a.go", "package model\n\n"+src+"\n"), nil)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestOutputName(t *testing.T) {
+	require.Equal(t, filepath.Join("model", "user_dynamorm.go"), outputName(filepath.Join("model", "user.go")))
+	require.Equal(t, "order_dynamorm_test.go", outputName("order_test.go"))
+}
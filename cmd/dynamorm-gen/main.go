@@ -0,0 +1,83 @@
+// Command dynamorm-gen generates the Entity methods, key constructors and typed query helpers
+// of structs declaring their keys as templates with the dynamorm tag, as understood by dynamorm.Keyed:
+//
+//	//go:generate go run github.com/vpriem/dynamorm/cmd/dynamorm-gen
+//
+//	//dynamorm:query OrdersByCustomer gsi1
+//	type Order struct {
+//	    _          struct{} `dynamorm:"pk=ORDER#{ID},sk=ORDER,gsi1pk=CUSTOMER#{CustomerID},gsi1sk=ORDER#{ID}"`
+//	    ID         string
+//	    CustomerID string
+//	}
+//
+// For each struct, it generates:
+//
+//   - key constructors, e.g. OrderPK(id string) string and OrderGSI1PK(customerID string) string
+//   - the PkSk, GSI1 and GSI2 methods, and BeforeSave unless the struct already has one
+//   - for each //dynamorm:query <Name> <table|gsi1|gsi2> directive, a Query<Name> function
+//     querying the table or the index by the fields of its partition key, with a sort key condition
+//     matching the literal prefix of its sort key, e.g. QueryOrdersByCustomer(ctx, storage, customerID, opts...)
+//
+// Like dynamorm.Keyed, a key is empty when one of its fields is the zero value, e.g. uuid.Nil or 0,
+// or formats to an empty string. Fields used in templates must be exported, and be strings, integers,
+// or implement fmt.Stringer; fields of types declared in other packages must also be comparable.
+//
+// Usage:
+//
+//	dynamorm-gen [-type Order,Customer] [-output file] [file]
+//
+// The file defaults to $GOFILE, as set by go generate, and the output to the file suffixed
+// with _dynamorm.go, or _dynamorm_test.go for test files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	types := flag.String("type", "", "comma-separated list of struct names; defaults to all the structs declaring keys")
+	output := flag.String("output", "", "output file name; defaults to <file>_dynamorm.go")
+	flag.Parse()
+
+	if err := run(flag.Arg(0), *types, *output); err != nil {
+		fmt.Fprintln(os.Stderr, "dynamorm-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(file, types, output string) error {
+	if file == "" {
+		file = os.Getenv("GOFILE")
+	}
+	if file == "" {
+		return fmt.Errorf("no input file")
+	}
+
+	var names []string
+	if types != "" {
+		names = strings.Split(types, ",")
+	}
+
+	src, err := generate(file, names)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = outputName(file)
+	}
+	return os.WriteFile(output, src, 0o644)
+}
+
+// outputName returns the default output file name of an input file.
+func outputName(file string) string {
+	dir, base := filepath.Split(file)
+	if name, ok := strings.CutSuffix(base, "_test.go"); ok {
+		return filepath.Join(dir, name+"_dynamorm_test.go")
+	}
+	return filepath.Join(dir, strings.TrimSuffix(base, ".go")+"_dynamorm.go")
+}
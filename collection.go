@@ -0,0 +1,152 @@
+package dynamorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Binding maps the items of a collection loaded by Storage.LoadCollection to a target,
+// either a pointer to an entity, e.g. *Customer, or a pointer to a slice of entity pointers, e.g. *[]*Order.
+type Binding struct {
+	match  func(keys KeySchema, item map[string]types.AttributeValue) bool
+	target any
+}
+
+// BindPrefix binds the items whose sort key begins with the given prefix to the target.
+func BindPrefix(prefix string, target any) Binding {
+	return Binding{
+		match: func(keys KeySchema, item map[string]types.AttributeValue) bool {
+			return strings.HasPrefix(keys.keys(item)[1], prefix)
+		},
+		target: target,
+	}
+}
+
+// BindType binds the items whose TypeAttribute is the given type name, as registered
+// with WithEntityType, to the target.
+func BindType(name string, target any) Binding {
+	return Binding{
+		match: func(_ KeySchema, item map[string]types.AttributeValue) bool {
+			v, ok := item[TypeAttribute].(*types.AttributeValueMemberS)
+			return ok && v.Value == name
+		},
+		target: target,
+	}
+}
+
+// validate checks that the target of the binding is a pointer to an entity,
+// or a pointer to a slice of entity pointers.
+func (b Binding) validate() error {
+	entity := reflect.TypeFor[Entity]()
+
+	v := reflect.ValueOf(b.target)
+	if b.match != nil && v.Kind() == reflect.Pointer && !v.IsNil() {
+		t := v.Type()
+		if t.Elem().Kind() == reflect.Struct && t.Implements(entity) {
+			return nil
+		}
+		if e := t.Elem(); e.Kind() == reflect.Slice {
+			if p := e.Elem(); p.Kind() == reflect.Pointer && p.Elem().Kind() == reflect.Struct && p.Implements(entity) {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("%w: %T", ErrInvalidBinding, b.target)
+}
+
+// bind decodes an item into the target of the binding: a new entity is appended to a slice target,
+// while an entity target is decoded into unless it is already bound.
+func (b Binding) bind(decoder DecoderInterface, keys KeySchema, item map[string]types.AttributeValue, bound bool) error {
+	v := reflect.ValueOf(b.target).Elem()
+	if v.Kind() != reflect.Slice {
+		if bound {
+			return nil
+		}
+		return decodeItem(decoder, keys, item, b.target.(Entity))
+	}
+
+	e := reflect.New(v.Type().Elem().Elem())
+	if err := decodeItem(decoder, keys, item, e.Interface().(Entity)); err != nil {
+		return err
+	}
+	v.Set(reflect.Append(v, e))
+	return nil
+}
+
+// LoadCollection loads a whole item collection, i.e. all the items sharing the partition key pk,
+// with a single query paging through the collection, and decodes every item into the target
+// of the first binding it matches. Items matching no binding are ignored.
+//
+// Entities are appended to slice targets, while an entity target receives the first item it matches.
+// Query options, e.g. QueryConsistent or QueryFilter, apply to the underlying query.
+// Returns ErrInvalidBinding if a target is neither a pointer to an entity nor a pointer to a slice
+// of entity pointers, and a NotFoundError (matching ErrEntityNotFound) listing the entity targets
+// that matched no item, once the rest of the collection is loaded.
+//
+// Example:
+//
+//	var customer Customer
+//	var orders []*Order
+//	err := storage.LoadCollection(ctx, "CUSTOMER#1", []dynamorm.Binding{
+//	    dynamorm.BindPrefix("PROFILE", &customer),
+//	    dynamorm.BindPrefix("ORDER#", &orders),
+//	})
+func (s *Storage) LoadCollection(ctx context.Context, pk string, bindings []Binding, opts ...QueryOption) error {
+	for _, b := range bindings {
+		if err := b.validate(); err != nil {
+			return err
+		}
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName: aws.String(s.table),
+	}
+	keyCond := expression.Key(s.keys.PK).Equal(
+		expression.Value(rawValue{keyValue(s.keys.PKType, pk)}),
+	)
+
+	query, err := s.newQuery(ctx, input, s.keys.PK, pk, keyCond, opts...)
+	if err != nil {
+		return err
+	}
+
+	bound := make([]bool, len(bindings))
+	for query.NextPage(ctx) {
+		for _, item := range query.output.Items {
+			for i, b := range bindings {
+				if !b.match(s.keys, item) {
+					continue
+				}
+				if err = b.bind(s.decoder, s.keys, item, bound[i]); err != nil {
+					return err
+				}
+				bound[i] = true
+				break
+			}
+		}
+	}
+
+	if err = query.Error(); err != nil {
+		return err
+	}
+
+	var unbound []Entity
+	for i, b := range bindings {
+		if e, ok := b.target.(Entity); ok && !bound[i] {
+			unbound = append(unbound, e)
+		}
+	}
+	if len(unbound) > 0 {
+		return NewNotFoundError(unbound)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package dynamorm
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// cursorAttribute is the placeholder attribute under which QueryStartFrom and ScanStartFrom
+// stash the raw cursor in the ExclusiveStartKey, until the storage decodes and validates it.
+const cursorAttribute = "dynamorm:cursor"
+
+// cursorPayload is the JSON document encoded in a cursor. It binds the last evaluated key
+// to the table and index it was read from.
+type cursorPayload struct {
+	Table string                 `json:"t"`
+	Index string                 `json:"i,omitempty"`
+	Key   map[string]cursorValue `json:"k"`
+}
+
+// cursorValue is the JSON representation of a key attribute value (S, N or B).
+type cursorValue struct {
+	S *string `json:"S,omitempty"`
+	N *string `json:"N,omitempty"`
+	B []byte  `json:"B,omitempty"`
+}
+
+// encodeCursor builds an opaque, URL-safe token from a last evaluated key. When a secret
+// is given, the token is signed with HMAC-SHA256 so it cannot be tampered with.
+func encodeCursor(table, index string, key map[string]types.AttributeValue, secret []byte) (string, error) {
+	payload := cursorPayload{Table: table, Index: index, Key: make(map[string]cursorValue, len(key))}
+	for name, av := range key {
+		switch v := av.(type) {
+		case *types.AttributeValueMemberS:
+			payload.Key[name] = cursorValue{S: &v.Value}
+		case *types.AttributeValueMemberN:
+			payload.Key[name] = cursorValue{N: &v.Value}
+		case *types.AttributeValueMemberB:
+			payload.Key[name] = cursorValue{B: v.Value}
+		default:
+			return "", fmt.Errorf("%w: unsupported key attribute type %T", ErrInvalidCursor, av)
+		}
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(data)
+	if len(secret) > 0 {
+		token += "." + base64.RawURLEncoding.EncodeToString(signCursor(token, secret))
+	}
+	return token, nil
+}
+
+// decodeCursor validates a token built by encodeCursor against the expected table and index
+// (and signature when a secret is given) and returns the key it holds.
+func decodeCursor(token, table, index string, secret []byte) (map[string]types.AttributeValue, error) {
+	data, sig, signed := strings.Cut(token, ".")
+	if len(secret) > 0 {
+		mac, err := base64.RawURLEncoding.DecodeString(sig)
+		if !signed || err != nil || !hmac.Equal(mac, signCursor(data, secret)) {
+			return nil, fmt.Errorf("%w: bad signature", ErrInvalidCursor)
+		}
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	var payload cursorPayload
+	if err = json.Unmarshal(raw, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if payload.Table != table || payload.Index != index {
+		return nil, fmt.Errorf("%w: issued for another table or index", ErrInvalidCursor)
+	}
+	if len(payload.Key) == 0 {
+		return nil, fmt.Errorf("%w: empty key", ErrInvalidCursor)
+	}
+
+	key := make(map[string]types.AttributeValue, len(payload.Key))
+	for name, v := range payload.Key {
+		switch {
+		case v.S != nil && v.N == nil && v.B == nil:
+			key[name] = &types.AttributeValueMemberS{Value: *v.S}
+		case v.N != nil && v.S == nil && v.B == nil:
+			key[name] = &types.AttributeValueMemberN{Value: *v.N}
+		case v.B != nil && v.S == nil && v.N == nil:
+			key[name] = &types.AttributeValueMemberB{Value: v.B}
+		default:
+			return nil, fmt.Errorf("%w: malformed key attribute %s", ErrInvalidCursor, name)
+		}
+	}
+
+	return key, nil
+}
+
+func signCursor(data string, secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// cursorStartKey returns the ExclusiveStartKey to send: when the given start key holds a cursor
+// set by QueryStartFrom or ScanStartFrom, the cursor is decoded and validated, otherwise the
+// start key is returned unchanged. When pkName is not empty, the decoded key must belong to
+// the partition pk so that clients cannot forge keys into other partitions.
+func cursorStartKey(startKey map[string]types.AttributeValue, table, index, pkName, pk string, secret []byte) (map[string]types.AttributeValue, error) {
+	v, ok := startKey[cursorAttribute].(*types.AttributeValueMemberS)
+	if !ok {
+		return startKey, nil
+	}
+
+	key, err := decodeCursor(v.Value, table, index, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if pkName != "" {
+		// Number keys are compared in canonical form, like DynamoDB does
+		if _, ok := key[pkName].(*types.AttributeValueMemberN); ok {
+			pk = canonicalNumber(pk)
+		}
+		if v, ok := keyString(key[pkName]); !ok || v != pk {
+			return nil, fmt.Errorf("%w: issued for another partition", ErrInvalidCursor)
+		}
+	}
+
+	return key, nil
+}
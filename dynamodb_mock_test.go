@@ -41,6 +41,26 @@ func (m *MockDynamoDB) EXPECT() *MockDynamoDBMockRecorder {
 	return m.recorder
 }
 
+// BatchGetItem mocks base method.
+func (m *MockDynamoDB) BatchGetItem(arg0 context.Context, arg1 *dynamodb.BatchGetItemInput, arg2 ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "BatchGetItem", varargs...)
+	ret0, _ := ret[0].(*dynamodb.BatchGetItemOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BatchGetItem indicates an expected call of BatchGetItem.
+func (mr *MockDynamoDBMockRecorder) BatchGetItem(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BatchGetItem", reflect.TypeOf((*MockDynamoDB)(nil).BatchGetItem), varargs...)
+}
+
 // BatchWriteItem mocks base method.
 func (m *MockDynamoDB) BatchWriteItem(arg0 context.Context, arg1 *dynamodb.BatchWriteItemInput, arg2 ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
 	m.ctrl.T.Helper()
@@ -161,26 +181,6 @@ func (mr *MockDynamoDBMockRecorder) PutItem(arg0, arg1 any, arg2 ...any) *gomock
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutItem", reflect.TypeOf((*MockDynamoDB)(nil).PutItem), varargs...)
 }
 
-// UpdateItem mocks base method.
-func (m *MockDynamoDB) UpdateItem(arg0 context.Context, arg1 *dynamodb.UpdateItemInput, arg2 ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
-	m.ctrl.T.Helper()
-	varargs := []any{arg0, arg1}
-	for _, a := range arg2 {
-		varargs = append(varargs, a)
-	}
-	ret := m.ctrl.Call(m, "UpdateItem", varargs...)
-	ret0, _ := ret[0].(*dynamodb.UpdateItemOutput)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// UpdateItem indicates an expected call of UpdateItem.
-func (mr *MockDynamoDBMockRecorder) UpdateItem(arg0, arg1 any, arg2 ...any) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	varargs := append([]any{arg0, arg1}, arg2...)
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateItem", reflect.TypeOf((*MockDynamoDB)(nil).UpdateItem), varargs...)
-}
-
 // Query mocks base method.
 func (m *MockDynamoDB) Query(arg0 context.Context, arg1 *dynamodb.QueryInput, arg2 ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
 	m.ctrl.T.Helper()
@@ -240,3 +240,43 @@ func (mr *MockDynamoDBMockRecorder) TransactWriteItems(arg0, arg1 any, arg2 ...a
 	varargs := append([]any{arg0, arg1}, arg2...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransactWriteItems", reflect.TypeOf((*MockDynamoDB)(nil).TransactWriteItems), varargs...)
 }
+
+// UpdateItem mocks base method.
+func (m *MockDynamoDB) UpdateItem(arg0 context.Context, arg1 *dynamodb.UpdateItemInput, arg2 ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateItem", varargs...)
+	ret0, _ := ret[0].(*dynamodb.UpdateItemOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateItem indicates an expected call of UpdateItem.
+func (mr *MockDynamoDBMockRecorder) UpdateItem(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateItem", reflect.TypeOf((*MockDynamoDB)(nil).UpdateItem), varargs...)
+}
+
+// UpdateTimeToLive mocks base method.
+func (m *MockDynamoDB) UpdateTimeToLive(arg0 context.Context, arg1 *dynamodb.UpdateTimeToLiveInput, arg2 ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "UpdateTimeToLive", varargs...)
+	ret0, _ := ret[0].(*dynamodb.UpdateTimeToLiveOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateTimeToLive indicates an expected call of UpdateTimeToLive.
+func (mr *MockDynamoDBMockRecorder) UpdateTimeToLive(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTimeToLive", reflect.TypeOf((*MockDynamoDB)(nil).UpdateTimeToLive), varargs...)
+}
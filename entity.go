@@ -9,23 +9,49 @@ package dynamorm
 // to DynamoDB's key schema and provides lifecycle hooks.
 type Entity interface {
 	// PkSk returns the partition key and sort key for the entity.
-	// During a save operation (Save or BatchSave) it is called and saves the values respectively in PK/SK columns.
+	// During a save operation (Save or BatchSave) it is called and saves the values respectively in PK/SK columns,
+	// or the attributes mapped by WithKeySchema.
 	// During a Get() operation it is called to know how to retrieve the entity.
 	// Both values must be non-empty for operations to succeed.
 	PkSk() (string, string)
 
 	// GSI1 returns the partition key and sort key for the first Global Secondary Index.
-	// During a save operation (Save or BatchSave) it is called and saves the values respectively in GSI1PK/GSI1SK columns.
+	// During a save operation (Save or BatchSave) it is called and saves the values respectively in GSI1PK/GSI1SK columns,
+	// or the attributes mapped by WithKeySchema.
 	// Return empty strings if not using this GSI.
 	GSI1() (string, string)
 
 	// GSI2 returns the partition key and sort key for the second Global Secondary Index.
-	// During a save operation (Save or BatchSave) it is called and saves the values respectively in GSI2PK/GSI2SK columns.
+	// During a save operation (Save or BatchSave) it is called and saves the values respectively in GSI2PK/GSI2SK columns,
+	// or the attributes mapped by WithKeySchema.
 	// Return empty strings if not using this GSI.
 	GSI2() (string, string)
 
 	// BeforeSave is called before the entity is saved to DynamoDB.
 	// This can be used to set timestamps, perform validation, etc.
 	// Return an error to abort the save operation.
+	// See AfterSaver, AfterLoader, BeforeUpdater, BeforeRemover and AfterRemover for the other hooks.
 	BeforeSave() error
 }
+
+// Tag is the struct tag holding the options read by dynamorm, separated by commas:
+// the validation rules of a field (see ValidationTag) and the key templates of a struct (see RegisterKeys).
+const Tag = "dynamorm"
+
+// IndexKey holds the partition key and sort key of an entity in a secondary index.
+// The partition key is ignored for local secondary indexes, which share the one of the table.
+type IndexKey struct {
+	PK string
+	SK string
+}
+
+// IndexedEntity is an optional interface for entities stored in more indexes than GSI1 and GSI2.
+// The indexes must be registered on the storage with WithIndexes.
+type IndexedEntity interface {
+	Entity
+
+	// IndexKeys returns the keys of the entity by index name. During a save operation the keys
+	// are saved in the attributes of the registered indexes; indexes that are not registered are ignored.
+	// Indexes missing from the map are left out, like the GSIs when GSI1() or GSI2() returns empty strings.
+	IndexKeys() map[string]IndexKey
+}
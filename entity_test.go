@@ -1,9 +1,13 @@
 package dynamorm_test
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/vpriem/dynamorm"
 )
 
 type TestEntity struct {
@@ -29,3 +33,321 @@ func (c *TestEntity) GSI2() (string, string) {
 func (c *TestEntity) BeforeSave() error {
 	return nil
 }
+
+type VersionedEntity struct {
+	ID   string
+	Name string
+	Ver  int64 `dynamodbav:"Version"`
+}
+
+func (v *VersionedEntity) PkSk() (string, string) {
+	return "PK#" + v.ID, "SK"
+}
+
+func (v *VersionedEntity) GSI1() (string, string) {
+	return "", ""
+}
+
+func (v *VersionedEntity) GSI2() (string, string) {
+	return "", ""
+}
+
+func (v *VersionedEntity) BeforeSave() error {
+	return nil
+}
+
+func (v *VersionedEntity) Version() int64 {
+	return v.Ver
+}
+
+func (v *VersionedEntity) SetVersion(version int64) {
+	v.Ver = version
+}
+
+type TimestampedEntity struct {
+	ID        string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (e *TimestampedEntity) PkSk() (string, string) {
+	return "PK#" + e.ID, "SK"
+}
+
+func (e *TimestampedEntity) GSI1() (string, string) {
+	return "", ""
+}
+
+func (e *TimestampedEntity) GSI2() (string, string) {
+	return "", ""
+}
+
+func (e *TimestampedEntity) BeforeSave() error {
+	return nil
+}
+
+func (e *TimestampedEntity) Timestamps() (time.Time, time.Time) {
+	return e.CreatedAt, e.UpdatedAt
+}
+
+func (e *TimestampedEntity) SetTimestamps(createdAt, updatedAt time.Time) {
+	e.CreatedAt, e.UpdatedAt = createdAt, updatedAt
+}
+
+type ExpirableEntity struct {
+	ID      string
+	Expires time.Time
+}
+
+func (e *ExpirableEntity) PkSk() (string, string) {
+	return "PK#" + e.ID, "SK"
+}
+
+func (e *ExpirableEntity) GSI1() (string, string) {
+	return "", ""
+}
+
+func (e *ExpirableEntity) GSI2() (string, string) {
+	return "", ""
+}
+
+func (e *ExpirableEntity) BeforeSave() error {
+	return nil
+}
+
+func (e *ExpirableEntity) ExpiresAt() time.Time {
+	return e.Expires
+}
+
+type AccountEntity struct {
+	ID     string
+	Name   string
+	Status string `dynamodbav:",omitempty"`
+}
+
+func (e *AccountEntity) PkSk() (string, string) {
+	return "PK#" + e.ID, "SK#" + e.ID
+}
+
+func (e *AccountEntity) GSI1() (string, string) {
+	return "GSI1PK#" + e.ID, "GSI1SK#" + e.ID
+}
+
+func (e *AccountEntity) GSI2() (string, string) {
+	return "GSI2PK#" + e.ID, "GSI2SK#" + e.ID
+}
+
+func (e *AccountEntity) BeforeSave() error {
+	return nil
+}
+
+type TrackedEntity struct {
+	dynamorm.Tracking
+	ID    string
+	Name  string
+	Email string `dynamodbav:",omitempty"`
+}
+
+func (e *TrackedEntity) PkSk() (string, string) {
+	return "PK#" + e.ID, "SK"
+}
+
+func (e *TrackedEntity) GSI1() (string, string) {
+	return "", ""
+}
+
+func (e *TrackedEntity) GSI2() (string, string) {
+	return "", ""
+}
+
+func (e *TrackedEntity) BeforeSave() error {
+	return nil
+}
+
+type SessionEntity struct {
+	ID string
+}
+
+func (e *SessionEntity) PkSk() (string, string) {
+	return "SESSION#" + e.ID, ""
+}
+
+func (e *SessionEntity) GSI1() (string, string) {
+	return "", ""
+}
+
+func (e *SessionEntity) GSI2() (string, string) {
+	return "", ""
+}
+
+func (e *SessionEntity) BeforeSave() error {
+	return nil
+}
+
+type OrderEntity struct {
+	ID     string
+	Status string
+}
+
+func (e *OrderEntity) PkSk() (string, string) {
+	return "ORDER#" + e.ID, "ORDER"
+}
+
+func (e *OrderEntity) GSI1() (string, string) {
+	return "", ""
+}
+
+func (e *OrderEntity) GSI2() (string, string) {
+	return "", ""
+}
+
+func (e *OrderEntity) BeforeSave() error {
+	return nil
+}
+
+func (e *OrderEntity) IndexKeys() map[string]dynamorm.IndexKey {
+	return map[string]dynamorm.IndexKey{
+		"GSI3":    {PK: "STATUS#" + e.Status, SK: "ORDER#" + e.ID},
+		"LSI1":    {PK: "ignored", SK: "STATUS#" + e.Status},
+		"Unknown": {PK: "unknown", SK: "unknown"},
+	}
+}
+
+type EventEntity struct {
+	Stream   string
+	Sequence int
+	Checksum string
+}
+
+func (e *EventEntity) PkSk() (string, string) {
+	return "STREAM#" + e.Stream, strconv.Itoa(e.Sequence)
+}
+
+func (e *EventEntity) GSI1() (string, string) {
+	return e.Checksum, ""
+}
+
+func (e *EventEntity) GSI2() (string, string) {
+	return "", ""
+}
+
+func (e *EventEntity) BeforeSave() error {
+	return nil
+}
+
+// PaddedEventEntity formats its sequence with leading zeros, which DynamoDB strips from number keys.
+type PaddedEventEntity struct {
+	EventEntity
+}
+
+func (e *PaddedEventEntity) PkSk() (string, string) {
+	return "STREAM#" + e.Stream, fmt.Sprintf("%03d", e.Sequence)
+}
+
+type HookEntity struct {
+	ID      string
+	Derived string   `dynamodbav:"-"`
+	Calls   []string `dynamodbav:"-"`
+	Fail    string   `dynamodbav:"-"`
+}
+
+func (e *HookEntity) PkSk() (string, string) {
+	return "HOOK#" + e.ID, "HOOK"
+}
+
+func (e *HookEntity) GSI1() (string, string) {
+	return "", ""
+}
+
+func (e *HookEntity) GSI2() (string, string) {
+	return "", ""
+}
+
+func (e *HookEntity) BeforeSave() error {
+	return e.call("BeforeSave")
+}
+
+func (e *HookEntity) AfterSave() error {
+	return e.call("AfterSave")
+}
+
+func (e *HookEntity) AfterLoad() error {
+	e.Derived = "derived#" + e.ID
+	return e.call("AfterLoad")
+}
+
+func (e *HookEntity) BeforeUpdate() error {
+	return e.call("BeforeUpdate")
+}
+
+func (e *HookEntity) BeforeRemove() error {
+	return e.call("BeforeRemove")
+}
+
+func (e *HookEntity) AfterRemove() error {
+	return e.call("AfterRemove")
+}
+
+func (e *HookEntity) call(hook string) error {
+	e.Calls = append(e.Calls, hook)
+	if e.Fail == hook {
+		return errors.New(hook + " failed")
+	}
+	return nil
+}
+
+type ProfileEntity struct {
+	ID       string `dynamorm:"required"`
+	Email    string `dynamorm:"required,max=16,email"`
+	Nickname string `dynamorm:"min=3"`
+	Age      int    `dynamorm:"max=150"`
+	Tags     []string
+	Rejected bool `dynamodbav:"-"`
+}
+
+func (e *ProfileEntity) PkSk() (string, string) {
+	return "PROFILE#" + e.ID, "PROFILE"
+}
+
+func (e *ProfileEntity) GSI1() (string, string) {
+	return "", ""
+}
+
+func (e *ProfileEntity) GSI2() (string, string) {
+	return "", ""
+}
+
+func (e *ProfileEntity) BeforeSave() error {
+	return nil
+}
+
+func (e *ProfileEntity) Validate() error {
+	if e.Rejected {
+		return errors.New("profile is rejected")
+	}
+	return nil
+}
+
+type TaggedEntity struct {
+	ID   string `dynamorm:"pk=TAG#{ID},required"`
+	Name string `dynamorm:"requird,max=3"`
+}
+
+type TaggedUser struct {
+	_       struct{} `dynamorm:"pk=USER#{ID},sk=USER,gsi1pk=USER#EMAIL,gsi1sk={Email}"`
+	ID      string
+	Email   string
+	Age     int
+	Derived string `dynamodbav:"-"`
+}
+
+func (u *TaggedUser) AfterLoad() error {
+	u.Derived = "derived#" + u.ID
+	return nil
+}
+
+type TaggedEvent struct {
+	_        struct{} `dynamorm:"pk=STREAM#{Stream}, sk={Sequence}"`
+	Stream   string
+	Sequence uint
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/vpriem/dynamorm"
 )
@@ -23,3 +24,47 @@ func TestClientError(t *testing.T) {
 	require.ErrorAs(t, err, &checkErr)
 	require.Equal(t, "throttled", *checkErr.Message)
 }
+
+func TestNotFoundError(t *testing.T) {
+	e := &TestEntity{}
+	err := dynamorm.NewNotFoundError([]dynamorm.Entity{e})
+	require.ErrorIs(t, err, dynamorm.ErrEntityNotFound)
+	require.EqualError(t, err, "entity not found: 1 entities")
+	require.Equal(t, []dynamorm.Entity{e}, err.Entities)
+}
+
+func TestBatchError(t *testing.T) {
+	e := &TestEntity{}
+	err := dynamorm.NewBatchError([]dynamorm.Entity{e}, nil)
+	require.ErrorIs(t, err, dynamorm.ErrBatch)
+	require.EqualError(t, err, "failed to process all items in batch: 1 entities")
+	require.Equal(t, []dynamorm.Entity{e}, err.Entities)
+
+	err = dynamorm.NewBatchError([]dynamorm.Entity{e}, dynamorm.NewClientError(assert.AnError))
+	require.ErrorIs(t, err, dynamorm.ErrBatch)
+	require.ErrorIs(t, err, dynamorm.ErrClient)
+	require.ErrorIs(t, err, assert.AnError)
+}
+
+func TestBatchErrorPerEntity(t *testing.T) {
+	e1, e2, e3 := &TestEntity{}, &TestEntity{}, &TestEntity{}
+	clientErr := dynamorm.NewClientError(assert.AnError)
+
+	err := &dynamorm.BatchError{
+		Entities: []dynamorm.Entity{e1, e2, e3},
+		Errors:   []error{clientErr, nil, clientErr},
+	}
+	require.ErrorIs(t, err, dynamorm.ErrBatch)
+	require.ErrorIs(t, err, dynamorm.ErrClient)
+	require.Equal(t, []error{clientErr}, err.Unwrap())
+	require.EqualError(t, err, "failed to process all items in batch: 3 entities: client error: "+assert.AnError.Error())
+}
+
+func TestValidationError(t *testing.T) {
+	err := &dynamorm.ValidationError{Fields: []dynamorm.FieldError{
+		{Field: "Email", Rule: "required", Message: "is required"},
+		{Message: "profile is rejected"},
+	}}
+	require.ErrorIs(t, err, dynamorm.ErrEntityValidation)
+	require.EqualError(t, err, "entity validation failed: Email is required; profile is rejected")
+}
@@ -3,6 +3,9 @@ package dynamorm
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"slices"
+	"strings"
 )
 
 // ErrEntityNotFound is returned by Storage.Get when an entity with the given PK/SK
@@ -29,8 +32,29 @@ var ErrEntityDecode = errors.New("failed to decode entity")
 // a save operation (e.g., Storage.Save).
 var ErrEntityBeforeSave = errors.New("failed to execute entity.BeforeSave")
 
-// ErrBatch is returned by batch write operations when DynamoDB responds with
-// unprocessed items that could not be written (affecting BatchSave/BatchRemove).
+// ErrEntityValidation is matched by ValidationError, returned when an entity breaks
+// its validation rules before a write (e.g., Storage.Save).
+var ErrEntityValidation = errors.New("entity validation failed")
+
+// ErrEntityAfterSave is returned when AfterSaver.AfterSave returns an error once an entity is saved.
+var ErrEntityAfterSave = errors.New("failed to execute entity.AfterSave")
+
+// ErrEntityAfterLoad is returned when AfterLoader.AfterLoad returns an error once an entity is decoded.
+var ErrEntityAfterLoad = errors.New("failed to execute entity.AfterLoad")
+
+// ErrEntityBeforeUpdate is returned when BeforeUpdater.BeforeUpdate returns an error,
+// aborting the update (e.g., Storage.Update).
+var ErrEntityBeforeUpdate = errors.New("failed to execute entity.BeforeUpdate")
+
+// ErrEntityBeforeRemove is returned when BeforeRemover.BeforeRemove returns an error,
+// aborting the removal (e.g., Storage.Remove).
+var ErrEntityBeforeRemove = errors.New("failed to execute entity.BeforeRemove")
+
+// ErrEntityAfterRemove is returned when AfterRemover.AfterRemove returns an error once an entity is removed.
+var ErrEntityAfterRemove = errors.New("failed to execute entity.AfterRemove")
+
+// ErrBatch is matched by BatchError, returned by batch operations when some items
+// could not be processed (affecting BatchGet/BatchSave/BatchRemove).
 var ErrBatch = errors.New("failed to process all items in batch")
 
 // ErrClient is used to wrap errors returned by the underlying DynamoDB client.
@@ -41,6 +65,43 @@ var ErrClient = errors.New("client error")
 // including when there are no items.
 var ErrIndexOutOfRange = errors.New("index out of range")
 
+// ErrVersionConflict is returned when a write of a Versioned entity is rejected because
+// the stored version no longer matches the version of the entity.
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrNoSortKey is returned by Storage.Query and Storage.QueryIndex when given a sort key condition
+// while the table or the index has no sort key (see KeySchema.NoSortKey).
+var ErrNoSortKey = errors.New("table has no sort key")
+
+// ErrUnknownIndex is returned by Storage.QueryIndex and Storage.ScanIndex when the index
+// is not registered on the storage.
+var ErrUnknownIndex = errors.New("unknown index")
+
+// ErrUnknownEntityType is returned by Query.DecodeAny when the TypeAttribute of an item
+// is missing or names a type not registered with WithEntityType.
+var ErrUnknownEntityType = errors.New("unknown entity type")
+
+// ErrInvalidBinding is returned by Storage.LoadCollection when the target of a binding is neither
+// a pointer to an entity nor a pointer to a slice of entity pointers.
+var ErrInvalidBinding = errors.New("invalid binding")
+
+// ErrInvalidKeyTemplate is returned by RegisterKeys, and by the storage methods using an entity
+// adapted by Keyed, when the key templates declared by the tags of a struct are invalid.
+var ErrInvalidKeyTemplate = errors.New("invalid key template")
+
+// ErrInvalidCursor is returned by Query.Cursor when a cursor cannot be built, and by
+// Storage.Query and Storage.Scan (and their GSI variants) when the cursor given to
+// QueryStartFrom or ScanStartFrom is malformed, tampered with, or was issued for
+// another table, index or partition.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrTTLDisabled is returned by Storage.EnableTTL when the storage was created without WithTTL.
+var ErrTTLDisabled = errors.New("time to live is disabled")
+
+// ErrSoftDeleteDisabled is returned by Storage.SoftRemove and Storage.Restore when the storage
+// was created without WithSoftDelete.
+var ErrSoftDeleteDisabled = errors.New("soft delete is disabled")
+
 // NewClientError wraps an error returned by the underlying DynamoDB client
 // in a ClientError.
 func NewClientError(err error) *ClientError {
@@ -71,3 +132,118 @@ func (e *ClientError) Unwrap() error {
 func (e *ClientError) Is(target error) bool {
 	return target == ErrClient
 }
+
+// NewNotFoundError creates a NotFoundError for the given entities.
+func NewNotFoundError(entities []Entity) *NotFoundError {
+	return &NotFoundError{entities}
+}
+
+// NotFoundError is returned by Storage.BatchGet when one or more of the requested
+// entities do not exist in DynamoDB, and by Storage.LoadCollection when entity targets
+// matched no item. It lists the entities that were left untouched.
+type NotFoundError struct {
+	Entities []Entity
+}
+
+// Error returns a human-readable message.
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%v: %d entities", ErrEntityNotFound, len(e.Entities))
+}
+
+// Is makes NotFoundError match ErrEntityNotFound when used with errors.Is.
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrEntityNotFound
+}
+
+// NewBatchError creates a BatchError for the given unprocessed entities, all sharing
+// the same optional underlying error.
+func NewBatchError(entities []Entity, err error) *BatchError {
+	errs := make([]error, len(entities))
+	for i := range errs {
+		errs[i] = err
+	}
+	return &BatchError{entities, errs}
+}
+
+// BatchError is returned by Storage.BatchGet, Storage.BatchSave and Storage.BatchRemove
+// when some entities could not be processed, either because DynamoDB kept reporting them
+// as unprocessed after all retries or because a request failed. It lists exactly those
+// entities so callers can resume, along with the error that affected each of them.
+type BatchError struct {
+	// Entities lists the entities that were not processed.
+	Entities []Entity
+	// Errors holds, for each entity of Entities, the error that prevented it from being
+	// processed, or nil when DynamoDB still reported it as unprocessed once retries ran out.
+	Errors []error
+}
+
+// Error returns a human-readable message.
+func (e *BatchError) Error() string {
+	msg := fmt.Sprintf("%v: %d entities", ErrBatch, len(e.Entities))
+	for _, err := range e.Unwrap() {
+		msg = fmt.Sprintf("%s: %v", msg, err)
+	}
+	return msg
+}
+
+// Unwrap exposes the distinct underlying errors (e.g. a ClientError) so callers can use
+// errors.Is / errors.As.
+func (e *BatchError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil && !slices.ContainsFunc(errs, func(seen error) bool { return sameError(seen, err) }) {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Is makes BatchError match ErrBatch when used with errors.Is.
+func (e *BatchError) Is(target error) bool {
+	return target == ErrBatch
+}
+
+// FieldError describes a validation problem of an entity field.
+type FieldError struct {
+	// Field is the name of the struct field, or empty for a problem of the whole entity.
+	Field string
+	// Rule is the broken rule declared with ValidationTag, or empty for a Validator error.
+	Rule string
+	// Message describes the problem.
+	Message string
+}
+
+// Error returns a human-readable message.
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + " " + e.Message
+}
+
+// ValidationError is returned by write operations when an entity breaks the rules declared with
+// ValidationTag or its Validator. It lists every problem found, and no request is sent.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error returns a human-readable message.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		msgs[i] = field.Error()
+	}
+	return fmt.Sprintf("%v: %s", ErrEntityValidation, strings.Join(msgs, "; "))
+}
+
+// Is makes ValidationError match ErrEntityValidation when used with errors.Is.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrEntityValidation
+}
+
+// sameError reports whether a and b are the same error value, without panicking
+// on error types that are not comparable.
+func sameError(a, b error) bool {
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta.Comparable() && a == b
+}
@@ -35,3 +35,14 @@ func GetAttribute(attrs ...string) GetOption {
 		return builder.WithProjection(proj)
 	}
 }
+
+// GetIncludeDeleted makes Storage.Get return items soft-deleted by Storage.SoftRemove,
+// which are otherwise treated as missing when soft delete is enabled with WithSoftDelete.
+func GetIncludeDeleted() GetOption {
+	return func(_ *dynamodb.GetItemInput, builder BuilderInterface) BuilderInterface {
+		if b, ok := builder.(*configBuilder); ok {
+			b.cfg.includeDeleted = true
+		}
+		return nil
+	}
+}
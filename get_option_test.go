@@ -42,3 +42,11 @@ func TestGetAttribute(t *testing.T) {
 	nextBuilder = dynamorm.GetAttribute("Attr1", "Attr2")(nil, builder)
 	require.Equal(t, builder, nextBuilder)
 }
+
+func TestGetIncludeDeleted(t *testing.T) {
+	input := &dynamodb.GetItemInput{}
+
+	nextBuilder := dynamorm.GetIncludeDeleted()(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Equal(t, &dynamodb.GetItemInput{}, input)
+}
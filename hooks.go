@@ -0,0 +1,81 @@
+package dynamorm
+
+import "fmt"
+
+// AfterSaver is an optional interface for entities to run code once they are saved by Save,
+// BatchSave, SaveChanges or a committed Transaction. BatchSave only calls it on the entities
+// that were written. An error is returned to the caller, but the entity is saved regardless.
+type AfterSaver interface {
+	AfterSave() error
+}
+
+// AfterLoader is an optional interface for entities to run code once they are decoded from an item,
+// e.g. to rebuild derived fields. It is called by Get, BatchGet, Query.Decode, Query.DecodeAny,
+// Query.First, Query.Last, ScanResult.Decode, LoadCollection, and Update with return values.
+type AfterLoader interface {
+	AfterLoad() error
+}
+
+// BeforeUpdater is an optional interface for entities to run code before they are updated by Update,
+// Patch, SaveChanges, SoftRemove, Restore or Transaction.AddUpdate. Return an error to abort the update.
+type BeforeUpdater interface {
+	BeforeUpdate() error
+}
+
+// BeforeRemover is an optional interface for entities to run code before they are removed by Remove,
+// SoftRemove, BatchRemove or Transaction.AddRemove. Return an error to abort the removal, e.g. of a customer with
+// open orders. BatchRemove calls it on all the entities before removing any of them.
+type BeforeRemover interface {
+	BeforeRemove() error
+}
+
+// AfterRemover is an optional interface for entities to run code once they are removed by Remove,
+// SoftRemove, BatchRemove or a committed Transaction. BatchRemove only calls it on the entities that were removed.
+type AfterRemover interface {
+	AfterRemove() error
+}
+
+func afterSave(e Entity) error {
+	if h, ok := Unwrap(e).(AfterSaver); ok {
+		if err := h.AfterSave(); err != nil {
+			return fmt.Errorf("%w: %v", ErrEntityAfterSave, err)
+		}
+	}
+	return nil
+}
+
+func afterLoad(e Entity) error {
+	if h, ok := Unwrap(e).(AfterLoader); ok {
+		if err := h.AfterLoad(); err != nil {
+			return fmt.Errorf("%w: %v", ErrEntityAfterLoad, err)
+		}
+	}
+	return nil
+}
+
+func beforeUpdate(e Entity) error {
+	if h, ok := Unwrap(e).(BeforeUpdater); ok {
+		if err := h.BeforeUpdate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrEntityBeforeUpdate, err)
+		}
+	}
+	return nil
+}
+
+func beforeRemove(e Entity) error {
+	if h, ok := Unwrap(e).(BeforeRemover); ok {
+		if err := h.BeforeRemove(); err != nil {
+			return fmt.Errorf("%w: %v", ErrEntityBeforeRemove, err)
+		}
+	}
+	return nil
+}
+
+func afterRemove(e Entity) error {
+	if h, ok := Unwrap(e).(AfterRemover); ok {
+		if err := h.AfterRemove(); err != nil {
+			return fmt.Errorf("%w: %v", ErrEntityAfterRemove, err)
+		}
+	}
+	return nil
+}
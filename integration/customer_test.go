@@ -45,6 +45,19 @@ func TestCustomer(t *testing.T) {
 		require.Equal(t, cust1.Id, found.Id)
 	})
 
+	t.Run("should batch get customers", func(t *testing.T) {
+		found := &Customer{Id: cust1.Id}
+		missing := &Customer{Id: uuid.New()}
+
+		err := storage.BatchGet(context.TODO(), found, missing)
+		require.ErrorIs(t, err, dynamorm.ErrEntityNotFound)
+
+		var notFoundErr *dynamorm.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		require.Equal(t, []dynamorm.Entity{missing}, notFoundErr.Entities)
+		require.Equal(t, cust1.Email, found.Email)
+	})
+
 	t.Run("should find customer by id using SCAN and pagination", func(t *testing.T) {
 		filter := expression.Name("Id").Equal(expression.Value(cust1.Id.String()))
 
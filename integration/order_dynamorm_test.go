@@ -0,0 +1,67 @@
+// Code generated by dynamorm-gen. DO NOT EDIT.
+
+package integration_test
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/vpriem/dynamorm"
+)
+
+// OrderPK returns the PK key of Order entities: ORDER#{Id}.
+func OrderPK(id uuid.UUID) string {
+	if id == *new(uuid.UUID) {
+		return ""
+	}
+	v0 := id.String()
+	if v0 == "" {
+		return ""
+	}
+	return "ORDER#" + v0
+}
+
+// OrderSK returns the SK key of Order entities: ORDER.
+func OrderSK() string {
+	return "ORDER"
+}
+
+// OrderGSI1PK returns the GSI1PK key of Order entities: CUSTOMER#{CustomerId}.
+func OrderGSI1PK(customerId uuid.UUID) string {
+	if customerId == *new(uuid.UUID) {
+		return ""
+	}
+	v0 := customerId.String()
+	if v0 == "" {
+		return ""
+	}
+	return "CUSTOMER#" + v0
+}
+
+// OrderGSI1SK returns the GSI1SK key of Order entities: ORDER#STATUS#{Status}.
+func OrderGSI1SK(status string) string {
+	if status == "" {
+		return ""
+	}
+	return "ORDER#STATUS#" + status
+}
+
+// PkSk implements dynamorm.Entity.
+func (o *Order) PkSk() (string, string) {
+	return OrderPK(o.Id), OrderSK()
+}
+
+// GSI1 implements dynamorm.Entity.
+func (o *Order) GSI1() (string, string) {
+	return OrderGSI1PK(o.CustomerId), OrderGSI1SK(o.Status)
+}
+
+// GSI2 implements dynamorm.Entity.
+func (o *Order) GSI2() (string, string) {
+	return "", ""
+}
+
+// QueryOrdersByCustomer queries the Order entities of GSI1 by their GSI1PK key: CUSTOMER#{CustomerId}.
+func QueryOrdersByCustomer(ctx context.Context, storage dynamorm.StorageInterface, customerId uuid.UUID, opts ...dynamorm.QueryOption) (dynamorm.QueryInterface, error) {
+	return storage.QueryGSI1(ctx, OrderGSI1PK(customerId), dynamorm.SkBeginsWith("ORDER#STATUS#"), opts...)
+}
@@ -2,7 +2,6 @@ package integration_test
 
 import (
 	"context"
-	"fmt"
 	"testing"
 	"time"
 
@@ -62,10 +61,7 @@ func TestOrder(t *testing.T) {
 	require.NoError(t, err)
 
 	t.Run("should find all orders by customer id", func(t *testing.T) {
-		pk := fmt.Sprintf("CUSTOMER#%s", cust.Id)
-		skCond := dynamorm.SkBeginsWith("ORDER#")
-
-		query, err := storage.QueryGSI1(context.TODO(), pk, skCond)
+		query, err := QueryOrdersByCustomer(context.TODO(), storage, cust.Id)
 		require.NoError(t, err)
 		require.Equal(t, int32(4), query.Count())
 
@@ -86,8 +82,19 @@ func TestOrder(t *testing.T) {
 		require.ElementsMatch(t, expected, orders)
 	})
 
+	t.Run("should collect all orders by customer id", func(t *testing.T) {
+		query, err := QueryOrdersByCustomer(context.TODO(), storage, cust.Id,
+			dynamorm.QueryLimit(1),
+		)
+		require.NoError(t, err)
+
+		orders, err := dynamorm.Collect[*Order](context.TODO(), query, 0)
+		require.NoError(t, err)
+		require.Len(t, orders, 4)
+	})
+
 	t.Run("should find all orders by customer id with Status=delivered", func(t *testing.T) {
-		pk := fmt.Sprintf("CUSTOMER#%s", cust.Id)
+		pk := OrderGSI1PK(cust.Id)
 
 		expected := []string{
 			ord2.Id.String(),
@@ -95,7 +102,7 @@ func TestOrder(t *testing.T) {
 		}
 
 		t.Run("using SK", func(t *testing.T) {
-			skCond := dynamorm.SkBeginsWith("ORDER#STATUS#delivered")
+			skCond := dynamorm.SkBeginsWith(OrderGSI1SK("delivered"))
 
 			q, err := storage.QueryGSI1(context.TODO(), pk, skCond)
 			require.NoError(t, err)
@@ -133,7 +140,7 @@ func TestOrder(t *testing.T) {
 	})
 
 	t.Run("should find all orders by customer id with Status=payed,cancelled", func(t *testing.T) {
-		pk := fmt.Sprintf("CUSTOMER#%s", cust.Id)
+		pk := OrderGSI1PK(cust.Id)
 		skCond := dynamorm.SkBeginsWith("ORDER#STATUS")
 
 		expected := []string{
@@ -185,9 +192,33 @@ func TestOrder(t *testing.T) {
 			require.ElementsMatch(t, expected, orders)
 		})
 	})
+
+	t.Run("should leave order without status out of GSI1", func(t *testing.T) {
+		ord5 := &Order{}
+		randomize(t, ord5)
+		ord5.CustomerId = cust.Id
+		ord5.Status = ""
+
+		// OrderGSI1SK is empty without status, so only GSI1PK is written
+		require.Empty(t, OrderGSI1SK(ord5.Status))
+		require.NoError(t, storage.Save(context.TODO(), ord5))
+
+		ord := &Order{Id: ord5.Id}
+		require.NoError(t, storage.Get(context.TODO(), ord))
+		require.Equal(t, cust.Id, ord.CustomerId)
+		require.Empty(t, ord.Status)
+
+		query, err := QueryOrdersByCustomer(context.TODO(), storage, cust.Id)
+		require.NoError(t, err)
+		require.Equal(t, int32(4), query.Count())
+	})
 }
 
+//go:generate go run ../cmd/dynamorm-gen
+
+//dynamorm:query OrdersByCustomer gsi1
 type Order struct {
+	_          struct{}  `dynamorm:"pk=ORDER#{Id},sk=ORDER,gsi1pk=CUSTOMER#{CustomerId},gsi1sk=ORDER#STATUS#{Status}"`
 	Id         uuid.UUID `fake:"{uuid}"`
 	CustomerId uuid.UUID `fake:"{uuid}"`
 	Status     string    `fake:"{state}"`
@@ -197,30 +228,6 @@ type Order struct {
 	clock func() time.Time `dynamodbav:"-"`
 }
 
-func (o *Order) PkSk() (string, string) {
-	pk := ""
-	sk := ""
-	if o.Id != uuid.Nil {
-		pk = fmt.Sprintf("ORDER#%s", o.Id)
-		sk = "ORDER"
-	}
-	return pk, sk
-}
-
-func (o *Order) GSI1() (string, string) {
-	pk := ""
-	sk := ""
-	if o.CustomerId != uuid.Nil {
-		pk = fmt.Sprintf("CUSTOMER#%s", o.CustomerId)
-		sk = fmt.Sprintf("ORDER#STATUS#%s", o.Status)
-	}
-	return pk, sk
-}
-
-func (o *Order) GSI2() (string, string) {
-	return "", ""
-}
-
 func (o *Order) BeforeSave() error {
 	clock := o.clock
 	if clock == nil {
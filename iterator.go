@@ -0,0 +1,92 @@
+package dynamorm
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+)
+
+// All returns an iterator over every item of a query or scan result, across all pages.
+// It works with any QueryInterface returned by Storage.Query, Storage.QueryGSI1/2 and
+// Storage.Scan/ScanGSI1/ScanGSI2, starting from the current position of the query.
+//
+// Each item is decoded into a freshly allocated T, where T is usually a pointer to the
+// entity struct. Decoding errors are yielded alongside the zero value of T and iteration
+// continues with the next item; a pagination error is yielded last and ends the iteration.
+//
+// Example:
+//
+//	for order, err := range dynamorm.All[*Order](ctx, query) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // process order
+//	}
+func All[T Entity](ctx context.Context, q QueryInterface) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for q.NextPage(ctx) {
+			for q.Next() {
+				if !yield(decodeNew[T](q)) {
+					return
+				}
+			}
+		}
+
+		if err := q.Error(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// Collect walks every page of a query or scan result and returns the decoded items.
+// It stops after max items when max is greater than zero, and on the first decoding
+// or pagination error, returning the items collected so far along with the error.
+//
+// Example:
+//
+//	orders, err := dynamorm.Collect[*Order](ctx, query, 100)
+func Collect[T Entity](ctx context.Context, q QueryInterface, max int) ([]T, error) {
+	var list []T
+	if max > 0 {
+		list = make([]T, 0, max)
+	}
+
+	for e, err := range All[T](ctx, q) {
+		if err != nil {
+			return list, err
+		}
+		list = append(list, e)
+		if max > 0 && len(list) >= max {
+			break
+		}
+	}
+
+	return list, nil
+}
+
+// decodeNew allocates a new T and decodes the current item of the query into it.
+func decodeNew[T Entity](q QueryInterface) (T, error) {
+	var e T
+	t := reflect.TypeOf(e)
+	if t == nil {
+		return e, fmt.Errorf("%w: cannot allocate interface type %s", ErrEntityDecode, reflect.TypeFor[T]())
+	}
+
+	if t.Kind() == reflect.Pointer {
+		e = reflect.New(t.Elem()).Interface().(T)
+		if err := q.Decode(e); err != nil {
+			var zero T
+			return zero, err
+		}
+		return e, nil
+	}
+
+	// Non-pointer entities are decoded through their address, which shares their method set.
+	if err := q.Decode(any(&e).(Entity)); err != nil {
+		var zero T
+		return zero, err
+	}
+	return e, nil
+}
@@ -0,0 +1,187 @@
+package dynamorm_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vpriem/dynamorm"
+	"go.uber.org/mock/gomock"
+)
+
+type ValueEntity struct {
+	Email string
+}
+
+func (v ValueEntity) PkSk() (string, string) { return "", "" }
+func (v ValueEntity) GSI1() (string, string) { return "", "" }
+func (v ValueEntity) GSI2() (string, string) { return "", "" }
+func (v ValueEntity) BeforeSave() error      { return nil }
+
+func TestAll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	ctx := context.TODO()
+
+	out1 := &dynamodb.ScanOutput{
+		Items: []map[string]types.AttributeValue{
+			{"Email": &types.AttributeValueMemberS{Value: "usr0@go.dev"}},
+			{"Email": &types.AttributeValueMemberS{Value: "usr1@go.dev"}},
+		},
+		LastEvaluatedKey: map[string]types.AttributeValue{
+			"lastKey": &types.AttributeValueMemberS{Value: "1"},
+		},
+	}
+	out2 := &dynamodb.ScanOutput{
+		Items: []map[string]types.AttributeValue{
+			{"Email": &types.AttributeValueMemberS{Value: "usr2@go.dev"}},
+		},
+	}
+
+	newScan := func() *dynamorm.Query {
+		in := &dynamodb.ScanInput{TableName: aws.String("Table")}
+		return dynamorm.NewQuery(dynamo, nil, in, dynamorm.NewOutputFromScanOutput(out1), nil)
+	}
+
+	t.Run("should iterate over all pages", func(t *testing.T) {
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(out2, nil)
+
+		var emails []string
+		for e, err := range dynamorm.All[*TestEntity](ctx, newScan()) {
+			require.NoError(t, err)
+			emails = append(emails, e.Email)
+		}
+
+		require.Equal(t, []string{"usr0@go.dev", "usr1@go.dev", "usr2@go.dev"}, emails)
+	})
+
+	t.Run("should decode value entities", func(t *testing.T) {
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(out2, nil)
+
+		var emails []string
+		for e, err := range dynamorm.All[ValueEntity](ctx, newScan()) {
+			require.NoError(t, err)
+			emails = append(emails, e.Email)
+		}
+
+		require.Equal(t, []string{"usr0@go.dev", "usr1@go.dev", "usr2@go.dev"}, emails)
+	})
+
+	t.Run("should stop when breaking out of the loop", func(t *testing.T) {
+		var emails []string
+		for e, err := range dynamorm.All[*TestEntity](ctx, newScan()) {
+			require.NoError(t, err)
+			emails = append(emails, e.Email)
+			break
+		}
+
+		require.Equal(t, []string{"usr0@go.dev"}, emails)
+	})
+
+	t.Run("should yield pagination error", func(t *testing.T) {
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(nil, assert.AnError)
+
+		var emails []string
+		var errs []error
+		for e, err := range dynamorm.All[*TestEntity](ctx, newScan()) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			emails = append(emails, e.Email)
+		}
+
+		require.Equal(t, []string{"usr0@go.dev", "usr1@go.dev"}, emails)
+		require.Len(t, errs, 1)
+		require.ErrorIs(t, errs[0], dynamorm.ErrClient)
+	})
+
+	t.Run("should yield decode error and continue", func(t *testing.T) {
+		dec := NewMockDecoderInterface(ctrl)
+		dec.EXPECT().Decode(out1.Items[0], gomock.Any()).Return(assert.AnError)
+		dec.EXPECT().Decode(out1.Items[1], gomock.Any()).Return(nil)
+
+		output := &dynamorm.Output{Items: out1.Items}
+		query := dynamorm.NewQuery(dynamo, nil, nil, output, dec)
+
+		var errs []error
+		var count int
+		for e, err := range dynamorm.All[*TestEntity](ctx, query) {
+			if err != nil {
+				require.Nil(t, e)
+				errs = append(errs, err)
+				continue
+			}
+			count++
+		}
+
+		require.Equal(t, 1, count)
+		require.Len(t, errs, 1)
+		require.ErrorIs(t, errs[0], dynamorm.ErrEntityDecode)
+	})
+
+	t.Run("should return error for interface types", func(t *testing.T) {
+		for _, err := range dynamorm.All[dynamorm.Entity](ctx, newScan()) {
+			require.ErrorIs(t, err, dynamorm.ErrEntityDecode)
+			break
+		}
+	})
+}
+
+func TestCollect(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	ctx := context.TODO()
+
+	out1 := &dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{
+			{"Email": &types.AttributeValueMemberS{Value: "usr0@go.dev"}},
+			{"Email": &types.AttributeValueMemberS{Value: "usr1@go.dev"}},
+		},
+		LastEvaluatedKey: map[string]types.AttributeValue{
+			"lastKey": &types.AttributeValueMemberS{Value: "1"},
+		},
+	}
+	out2 := &dynamodb.QueryOutput{
+		Items: []map[string]types.AttributeValue{
+			{"Email": &types.AttributeValueMemberS{Value: "usr2@go.dev"}},
+		},
+	}
+
+	newQuery := func() *dynamorm.Query {
+		in := &dynamodb.QueryInput{TableName: aws.String("Table")}
+		return dynamorm.NewQuery(dynamo, in, nil, dynamorm.NewOutputFromQueryOutput(out1), nil)
+	}
+
+	t.Run("should collect all items", func(t *testing.T) {
+		dynamo.EXPECT().Query(ctx, gomock.Any()).Return(out2, nil)
+
+		list, err := dynamorm.Collect[*TestEntity](ctx, newQuery(), 0)
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+		require.Equal(t, "usr2@go.dev", list[2].Email)
+	})
+
+	t.Run("should collect up to max items", func(t *testing.T) {
+		list, err := dynamorm.Collect[*TestEntity](ctx, newQuery(), 2)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		require.Equal(t, "usr1@go.dev", list[1].Email)
+	})
+
+	t.Run("should return collected items and error", func(t *testing.T) {
+		dynamo.EXPECT().Query(ctx, gomock.Any()).Return(nil, assert.AnError)
+
+		list, err := dynamorm.Collect[*TestEntity](ctx, newQuery(), 10)
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+		require.Len(t, list, 2)
+	})
+}
@@ -0,0 +1,234 @@
+package dynamorm
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// keyOptions are the options of the dynamorm struct tag declaring the key templates of a struct.
+var keyOptions = []string{"pk", "sk", "gsi1pk", "gsi1sk", "gsi2pk", "gsi2sk"}
+
+// keyTemplates caches the parsed key templates, or the parsing error, by struct type.
+var keyTemplates sync.Map
+
+// keyTemplate is a key made of literal text and field placeholders, e.g. USER#{ID}.
+type keyTemplate []keyPart
+
+// keyPart is either literal text, or a placeholder when field is set.
+type keyPart struct {
+	text  string
+	field []int
+}
+
+// render returns the key of a struct value, or an empty string when a placeholder is the zero value
+// or formats to an empty string, so that keys with missing values are not written.
+func (t keyTemplate) render(v reflect.Value) string {
+	var b strings.Builder
+	for _, part := range t {
+		if part.field == nil {
+			b.WriteString(part.text)
+			continue
+		}
+		s := formatKey(v.FieldByIndex(part.field))
+		if s == "" {
+			return ""
+		}
+		b.WriteString(s)
+	}
+	return b.String()
+}
+
+// formatKey formats a field value as a key, or returns an empty string for the zero value,
+// e.g. uuid.Nil or 0.
+func formatKey(v reflect.Value) string {
+	if v.IsZero() {
+		return ""
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	default:
+		return ""
+	}
+}
+
+// keyable reports whether a field type can be used in a key placeholder.
+func keyable(t reflect.Type) bool {
+	if t.Implements(reflect.TypeFor[fmt.Stringer]()) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseKeyTemplate parses a key template of a struct type.
+func parseKeyTemplate(t reflect.Type, template string) (keyTemplate, error) {
+	var parts keyTemplate
+	for template != "" {
+		start := strings.IndexAny(template, "{}")
+		if start < 0 {
+			parts = append(parts, keyPart{text: template})
+			break
+		}
+		if template[start] == '}' {
+			return nil, fmt.Errorf("unexpected } in %q", template)
+		}
+		if start > 0 {
+			parts = append(parts, keyPart{text: template[:start]})
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed { in %q", template)
+		}
+		name := template[start+1 : start+end]
+		field, ok := t.FieldByName(name)
+		if !ok || !field.IsExported() {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		if !keyable(field.Type) {
+			return nil, fmt.Errorf("field %q of type %s cannot be used in a key", name, field.Type)
+		}
+		parts = append(parts, keyPart{field: field.Index})
+
+		template = template[start+end+1:]
+	}
+	return parts, nil
+}
+
+// parseKeyTemplates parses the key options of the dynamorm tags of a struct type.
+func parseKeyTemplates(t reflect.Type) (map[string]keyTemplate, error) {
+	if t == nil || t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %s is not a pointer to a struct", ErrInvalidKeyTemplate, t)
+	}
+	t = t.Elem()
+
+	templates := make(map[string]keyTemplate)
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := t.Field(i).Tag.Lookup(Tag)
+		if !ok {
+			continue
+		}
+		for _, option := range strings.Split(tag, ",") {
+			name, template, _ := strings.Cut(strings.TrimSpace(option), "=")
+			if !slices.Contains(keyOptions, name) {
+				continue
+			}
+			if _, ok := templates[name]; ok {
+				return nil, fmt.Errorf("%w: %s: duplicate %s", ErrInvalidKeyTemplate, t, name)
+			}
+			parts, err := parseKeyTemplate(t, template)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %s: %v", ErrInvalidKeyTemplate, t, name, err)
+			}
+			templates[name] = parts
+		}
+	}
+
+	if templates["pk"] == nil {
+		return nil, fmt.Errorf("%w: %s: missing pk", ErrInvalidKeyTemplate, t)
+	}
+	return templates, nil
+}
+
+type keyTemplatesResult struct {
+	templates map[string]keyTemplate
+	err       error
+}
+
+// keyTemplatesOf returns the cached key templates of a struct type, parsing them on first use.
+func keyTemplatesOf(t reflect.Type) (map[string]keyTemplate, error) {
+	if r, ok := keyTemplates.Load(t); ok {
+		return r.(keyTemplatesResult).templates, r.(keyTemplatesResult).err
+	}
+	templates, err := parseKeyTemplates(t)
+	keyTemplates.Store(t, keyTemplatesResult{templates, err})
+	return templates, err
+}
+
+// RegisterKeys parses and validates the key templates declared by the struct v points to, and caches them
+// for Keyed. Templates are declared with the pk, sk, gsi1pk, gsi1sk, gsi2pk and gsi2sk options of the
+// dynamorm tag, usually on a blank field, where {Field} is replaced by the value of an exported field:
+//
+//	type User struct {
+//	    _     struct{} `dynamorm:"pk=USER#{ID},sk=USER,gsi1pk=USER#EMAIL,gsi1sk={Email}"`
+//	    ID    string
+//	    Email string
+//	}
+//
+// Fields used in templates must be strings, integers, or implement fmt.Stringer. A key is empty, and thus
+// not written for GSIs, when one of its fields is the zero value, e.g. uuid.Nil or 0, or formats to an empty
+// string. Returns ErrInvalidKeyTemplate if a template is invalid.
+func RegisterKeys(v any) error {
+	_, err := keyTemplatesOf(reflect.TypeOf(v))
+	return err
+}
+
+// keyed adapts a struct with key templates to the Entity interface.
+type keyed struct {
+	v         any
+	templates map[string]keyTemplate
+	err       error
+}
+
+// Keyed adapts a pointer to a struct declaring key templates (see RegisterKeys) to the Entity interface,
+// so that it can be passed to Storage and Transaction methods without implementing PkSk, GSI1, GSI2
+// and BeforeSave. The struct is encoded and decoded as is, and the optional interfaces it implements,
+// such as Versioned, Timestamped or AfterLoader, are honoured, as is its BeforeSave method if any.
+//
+// If the templates are invalid, the entity has empty keys and the methods using it return
+// ErrInvalidKeyTemplate; call RegisterKeys at startup to catch invalid templates early.
+func Keyed(v any) Entity {
+	templates, err := keyTemplatesOf(reflect.TypeOf(v))
+	return &keyed{v, templates, err}
+}
+
+func (k *keyed) key(pkOption, skOption string) (string, string) {
+	v := reflect.ValueOf(k.v).Elem()
+	return k.templates[pkOption].render(v), k.templates[skOption].render(v)
+}
+
+func (k *keyed) PkSk() (string, string) {
+	return k.key("pk", "sk")
+}
+
+func (k *keyed) GSI1() (string, string) {
+	return k.key("gsi1pk", "gsi1sk")
+}
+
+func (k *keyed) GSI2() (string, string) {
+	return k.key("gsi2pk", "gsi2sk")
+}
+
+func (k *keyed) BeforeSave() error {
+	if h, ok := k.v.(interface{ BeforeSave() error }); ok {
+		return h.BeforeSave()
+	}
+	return nil
+}
+
+// Unwrap returns the struct adapted by Keyed, or the entity itself, e.g. to type switch
+// on the entities returned by Query.DecodeAny.
+func Unwrap(e Entity) any {
+	if k, ok := e.(*keyed); ok {
+		return k.v
+	}
+	return e
+}
@@ -0,0 +1,277 @@
+package dynamorm
+
+import (
+	"maps"
+	"math/big"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// KeySchema maps the keys of entities to the attribute and index names of the table.
+type KeySchema struct {
+	// PK and SK are the partition and sort key attributes of the table, populated by Entity.PkSk().
+	PK string
+	SK string
+	// PKType and SKType are the types of the PK and SK attributes, S by default.
+	PKType types.ScalarAttributeType
+	SKType types.ScalarAttributeType
+	// NoSortKey declares that the table has a partition key only: keys are made of PK alone,
+	// and the sort key returned by Entity.PkSk() is ignored.
+	NoSortKey bool
+	// GSI1 and GSI2 are the global secondary indexes populated by Entity.GSI1() and Entity.GSI2().
+	GSI1 IndexSchema
+	GSI2 IndexSchema
+	// Indexes are additional indexes, populated by IndexedEntity.IndexKeys().
+	Indexes []IndexSchema
+}
+
+// IndexSchema holds the name and the key attributes of a secondary index.
+// Key values, returned as strings by entities, are converted to the type of their attribute:
+// N values must be numbers, and B values are the bytes of the strings.
+type IndexSchema struct {
+	Name string
+	PK   string
+	// SK is the sort key attribute of the index, if any.
+	SK string
+	// PKType and SKType are the types of the PK and SK attributes, S by default.
+	PKType types.ScalarAttributeType
+	SKType types.ScalarAttributeType
+	// Local declares a local secondary index, sharing the partition key of the table:
+	// PK is ignored and only SK is written.
+	Local bool
+}
+
+// DefaultKeySchema returns the key schema used by default: PK and SK for the table,
+// and GSI1PK/GSI1SK and GSI2PK/GSI2SK for the indexes GSI1 and GSI2.
+func DefaultKeySchema() KeySchema {
+	return KeySchema{
+		PK:   "PK",
+		SK:   "SK",
+		GSI1: IndexSchema{Name: "GSI1", PK: "GSI1PK", SK: "GSI1SK"},
+		GSI2: IndexSchema{Name: "GSI2", PK: "GSI2PK", SK: "GSI2SK"},
+	}
+}
+
+// merge returns the schema with its empty names replaced by the ones of defaults.
+func (k KeySchema) merge(defaults KeySchema) KeySchema {
+	k.PK = or(k.PK, defaults.PK)
+	k.SK = or(k.SK, defaults.SK)
+	if k.NoSortKey {
+		k.SK = ""
+	}
+	k.GSI1 = k.GSI1.merge(defaults.GSI1)
+	k.GSI2 = k.GSI2.merge(defaults.GSI2)
+	k.Indexes = slices.Clone(k.Indexes)
+	for i, index := range k.Indexes {
+		if index.Local {
+			k.Indexes[i].PK = ""
+		}
+	}
+	return k
+}
+
+func (i IndexSchema) merge(defaults IndexSchema) IndexSchema {
+	i.Name = or(i.Name, defaults.Name)
+	i.PK = or(i.PK, defaults.PK)
+	i.SK = or(i.SK, defaults.SK)
+	return i
+}
+
+func or(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// rawValue hands an already encoded attribute value to the expression builder as is.
+type rawValue struct {
+	av types.AttributeValue
+}
+
+func (v rawValue) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	return v.av, nil
+}
+
+// keyValue converts a key value to an attribute of the given type, S by default.
+func keyValue(t types.ScalarAttributeType, value string) types.AttributeValue {
+	switch t {
+	case types.ScalarAttributeTypeN:
+		return &types.AttributeValueMemberN{Value: value}
+	case types.ScalarAttributeTypeB:
+		return &types.AttributeValueMemberB{Value: []byte(value)}
+	default:
+		return &types.AttributeValueMemberS{Value: value}
+	}
+}
+
+// keyString returns the value of a key attribute as a string, or false if it is not a key type.
+// Numbers are returned in canonical form, see canonicalNumber.
+func keyString(av types.AttributeValue) (string, bool) {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value, true
+	case *types.AttributeValueMemberN:
+		return canonicalNumber(v.Value), true
+	case *types.AttributeValueMemberB:
+		return string(v.Value), true
+	default:
+		return "", false
+	}
+}
+
+// canonicalNumber returns a number in canonical decimal form, so that numbers that DynamoDB stores as
+// the same value compare equal, e.g. "007" and "7" or "1.0" and "1". Invalid numbers are returned unchanged.
+func canonicalNumber(value string) string {
+	f, _, err := big.ParseFloat(value, 10, 256, big.ToNearestEven)
+	if err != nil || f.IsInf() {
+		return value
+	}
+	if f.Sign() == 0 {
+		return "0"
+	}
+	return f.Text('f', -1)
+}
+
+// entityKey returns the primary key of an entity, ignoring its sort key when the table has none.
+func (k KeySchema) entityKey(e Entity) (string, string, error) {
+	if ke, ok := e.(*keyed); ok && ke.err != nil {
+		return "", "", ke.err
+	}
+	pk, sk := e.PkSk()
+	if pk == "" {
+		return "", "", ErrEntityPkNotSet
+	}
+	if k.NoSortKey {
+		return pk, "", nil
+	}
+	if sk == "" {
+		return "", "", ErrEntitySkNotSet
+	}
+	return pk, sk, nil
+}
+
+// key returns the primary key of an item.
+func (k KeySchema) key(pk, sk string) map[string]types.AttributeValue {
+	key := map[string]types.AttributeValue{
+		k.PK: keyValue(k.PKType, pk),
+	}
+	if !k.NoSortKey {
+		key[k.SK] = keyValue(k.SKType, sk)
+	}
+	return key
+}
+
+// keys returns the PK/SK string values of an item or a key,
+// using empty strings for missing attributes, or when the table has no sort key.
+func (k KeySchema) keys(item map[string]types.AttributeValue) [2]string {
+	var key [2]string
+	for i, name := range []string{k.PK, k.SK} {
+		key[i], _ = keyString(item[name])
+	}
+	return key
+}
+
+// indexKey is the key of an entity in a secondary index.
+type indexKey struct {
+	IndexSchema
+	pk string
+	sk string
+}
+
+// values returns the key attributes of the entity in the index,
+// empty when the entity is not part of the index.
+func (i indexKey) values() map[string]types.AttributeValue {
+	values := make(map[string]types.AttributeValue, 2)
+	if i.Local {
+		if i.sk != "" {
+			values[i.SK] = keyValue(i.SKType, i.sk)
+		}
+		return values
+	}
+	if i.pk != "" {
+		values[i.PK] = keyValue(i.PKType, i.pk)
+		if i.SK != "" && i.sk != "" {
+			values[i.SK] = keyValue(i.SKType, i.sk)
+		}
+	}
+	return values
+}
+
+// attributes returns the key attributes of the index written by the storage.
+func (i IndexSchema) attributes() []string {
+	var names []string
+	for _, name := range []string{i.PK, i.SK} {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// indexKeys returns the keys of an entity in the secondary indexes.
+func (k KeySchema) indexKeys(e Entity) []indexKey {
+	gsi1pk, gsi1sk := e.GSI1()
+	gsi2pk, gsi2sk := e.GSI2()
+	keys := []indexKey{
+		{k.GSI1, gsi1pk, gsi1sk},
+		{k.GSI2, gsi2pk, gsi2sk},
+	}
+
+	var values map[string]IndexKey
+	if ie, ok := e.(IndexedEntity); ok {
+		values = ie.IndexKeys()
+	}
+	for _, index := range k.Indexes {
+		v := values[index.Name]
+		keys = append(keys, indexKey{index, v.PK, v.SK})
+	}
+	return keys
+}
+
+// indexes returns all the secondary indexes.
+func (k KeySchema) indexes() []IndexSchema {
+	return append([]IndexSchema{k.GSI1, k.GSI2}, k.Indexes...)
+}
+
+// index returns the secondary index of the given name.
+func (k KeySchema) index(name string) (IndexSchema, bool) {
+	for _, index := range k.indexes() {
+		if index.Name == name {
+			return index, true
+		}
+	}
+	return IndexSchema{}, false
+}
+
+// attributes returns the names of all the key attributes.
+func (k KeySchema) attributes() []string {
+	names := []string{k.PK}
+	if !k.NoSortKey {
+		names = append(names, k.SK)
+	}
+	for _, index := range k.indexes() {
+		names = append(names, index.attributes()...)
+	}
+	return names
+}
+
+// withoutKeys returns a copy of an item without its key attributes, so that they are not decoded
+// into entity fields whose names only differ in case, e.g. the pk attribute into a Pk field.
+// Entities hold their keys in PkSk(), GSI1() and GSI2() instead.
+func (k KeySchema) withoutKeys(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	out := maps.Clone(item)
+	for _, name := range k.attributes() {
+		delete(out, name)
+	}
+	return out
+}
+
+// setKeys sets the primary key and the index keys of an entity on its item.
+func (k KeySchema) setKeys(item map[string]types.AttributeValue, e Entity, pk, sk string) {
+	maps.Copy(item, k.key(pk, sk))
+	for _, index := range k.indexKeys(e) {
+		maps.Copy(item, index.values())
+	}
+}
@@ -1,18 +1,53 @@
 package dynamorm
 
+import (
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
 // Options contains configuration options for Storage.
 type Options struct {
 	Encoder    EncoderInterface
 	Decoder    DecoderInterface
 	NewBuilder CreateBuilder
+	// BatchRetries is the maximum number of times unprocessed batch items are re-submitted.
+	BatchRetries int
+	// BatchRetryDelay is the base delay of the exponential backoff between batch retries.
+	BatchRetryDelay time.Duration
+	// BatchConcurrency is the maximum number of BatchWriteItem requests in flight at once.
+	BatchConcurrency int
+	// CursorSecret, when set, is used to sign and verify pagination cursors with HMAC-SHA256.
+	CursorSecret []byte
+	// ConsumedCapacity is the level of consumed capacity requested on every operation.
+	ConsumedCapacity types.ReturnConsumedCapacity
+	// Clock returns the current time used for the timestamps of Timestamped entities
+	// and to detect expired items of Expirable entities.
+	Clock func() time.Time
+	// KeySchema maps the keys of entities to the attribute and index names of the table.
+	KeySchema KeySchema
+	// Registry holds the entity types registered with WithEntityType.
+	Registry *Registry
+	// TTLAttribute, when set with WithTTL, is the attribute holding the expiry time of Expirable entities.
+	// Time to Live is disabled when empty.
+	TTLAttribute string
+	// DeletedAtAttribute, when set with WithSoftDelete, is the attribute marking items as soft-deleted.
+	// Soft delete is disabled when empty.
+	DeletedAtAttribute string
 }
 
 // DefaultOptions creates default options for the storage, providing default encoder and decoder.
 func DefaultOptions() *Options {
 	return &Options{
-		Encoder:    DefaultEncoder(),
-		Decoder:    DefaultDecoder(),
-		NewBuilder: NewBuilder,
+		Encoder:          DefaultEncoder(),
+		Decoder:          DefaultDecoder(),
+		NewBuilder:       NewBuilder,
+		BatchRetries:     5,
+		BatchRetryDelay:  50 * time.Millisecond,
+		BatchConcurrency: 1,
+		Clock:            time.Now,
+		KeySchema:        DefaultKeySchema(),
 	}
 }
 
@@ -46,3 +81,122 @@ func WithBuilder(nb CreateBuilder) Option {
 		}
 	}
 }
+
+// WithBatchRetry configures how unprocessed items of BatchGet, BatchSave and BatchRemove
+// are re-submitted: up to retries times, waiting an exponentially growing, jittered
+// delay starting from the given base delay and capped at 10 seconds. Negative values are ignored.
+func WithBatchRetry(retries int, delay time.Duration) Option {
+	return func(cfg *Options) {
+		if retries >= 0 {
+			cfg.BatchRetries = retries
+		}
+		if delay >= 0 {
+			cfg.BatchRetryDelay = delay
+		}
+	}
+}
+
+// WithBatchConcurrency sets how many chunks BatchSave and BatchRemove send in parallel.
+// It bounds the number of in-flight BatchWriteItem requests; the default of 1 sends
+// chunks one after another. Values lower than 1 are ignored.
+func WithBatchConcurrency(n int) Option {
+	return func(cfg *Options) {
+		if n >= 1 {
+			cfg.BatchConcurrency = n
+		}
+	}
+}
+
+// WithCursorSecret signs the cursors returned by Query.Cursor with HMAC-SHA256 using the
+// given secret, and rejects cursors given to QueryStartFrom or ScanStartFrom that were not
+// signed with it. Use it whenever cursors are handed to untrusted clients.
+func WithCursorSecret(secret []byte) Option {
+	return func(cfg *Options) {
+		if len(secret) > 0 {
+			cfg.CursorSecret = secret
+		}
+	}
+}
+
+// WithConsumedCapacity requests DynamoDB to return the capacity consumed by every operation,
+// either types.ReturnConsumedCapacityTotal or types.ReturnConsumedCapacityIndexes for a breakdown
+// per table and index. It is reported by Query.ConsumedCapacity, accumulated by
+// Storage.ConsumedCapacity, and collected through WithCapacity. Other values are ignored.
+func WithConsumedCapacity(level types.ReturnConsumedCapacity) Option {
+	return func(cfg *Options) {
+		if level == types.ReturnConsumedCapacityTotal || level == types.ReturnConsumedCapacityIndexes {
+			cfg.ConsumedCapacity = level
+		}
+	}
+}
+
+// WithClock sets the clock used for the timestamps of Timestamped entities and the expiry
+// of Expirable entities, which defaults to time.Now. Useful to make tests deterministic. A nil clock is ignored.
+func WithClock(clock func() time.Time) Option {
+	return func(cfg *Options) {
+		if clock != nil {
+			cfg.Clock = clock
+		}
+	}
+}
+
+// WithTTL enables Time to Live: Expirable entities are written with their expiry time under the given attribute,
+// or TTLAttribute if empty, and expired items are left out by Get, BatchGet, Query, Scan and their counts.
+func WithTTL(attribute string) Option {
+	return func(cfg *Options) {
+		if attribute == "" {
+			attribute = TTLAttribute
+		}
+		cfg.TTLAttribute = attribute
+	}
+}
+
+// WithSoftDelete enables Storage.SoftRemove and Storage.Restore, marking items as deleted with the given attribute,
+// or DeletedAtAttribute if empty. Soft-deleted items are left out by Get, BatchGet, Query, Scan and their counts,
+// unless an IncludeDeleted option is passed.
+func WithSoftDelete(attribute string) Option {
+	return func(cfg *Options) {
+		if attribute == "" {
+			attribute = DeletedAtAttribute
+		}
+		cfg.DeletedAtAttribute = attribute
+	}
+}
+
+// WithKeySchema maps the keys of entities to the attribute and index names of the table,
+// for tables not using the names of DefaultKeySchema. Empty names keep their default.
+// Key attributes are left out when items are decoded into entities.
+// Indexes registered with WithIndexes are kept.
+func WithKeySchema(schema KeySchema) Option {
+	return func(cfg *Options) {
+		schema.Indexes = append(slices.Clip(cfg.KeySchema.Indexes), schema.Indexes...)
+		cfg.KeySchema = schema.merge(DefaultKeySchema())
+	}
+}
+
+// WithIndexes registers secondary indexes besides GSI1 and GSI2, populated by IndexedEntity.IndexKeys()
+// and read with Storage.QueryIndex and Storage.ScanIndex. Indexes without a name are ignored.
+func WithIndexes(indexes ...IndexSchema) Option {
+	return func(cfg *Options) {
+		for _, index := range indexes {
+			if index.Name != "" {
+				cfg.KeySchema.Indexes = append(cfg.KeySchema.Indexes, index)
+			}
+		}
+		cfg.KeySchema = cfg.KeySchema.merge(DefaultKeySchema())
+	}
+}
+
+// WithEntityType registers an entity type under the given name: its items are saved with the name
+// under TypeAttribute, and decoded into entities of the type by Query.DecodeAny. The entity is only
+// used for its type and must be a pointer to a struct, e.g. WithEntityType("order", &Order{}),
+// or a struct adapted by Keyed, e.g. WithEntityType("user", Keyed(&User{})).
+// Empty names and entities of other kinds are ignored.
+func WithEntityType(name string, e Entity) Option {
+	return func(cfg *Options) {
+		if cfg.Registry == nil {
+			cfg.Registry = NewRegistry()
+		}
+		cfg.Registry.Register(name, e)
+	}
+}
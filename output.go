@@ -11,6 +11,7 @@ type Output struct {
 	ScannedCount     int32
 	Items            []map[string]types.AttributeValue
 	LastEvaluatedKey map[string]types.AttributeValue
+	ConsumedCapacity *types.ConsumedCapacity
 }
 
 // NewOutputFromQueryOutput converts an AWS SDK DynamoDB QueryOutput to the library's Output type.
@@ -20,6 +21,7 @@ func NewOutputFromQueryOutput(q *dynamodb.QueryOutput) *Output {
 		ScannedCount:     q.ScannedCount,
 		Items:            q.Items,
 		LastEvaluatedKey: q.LastEvaluatedKey,
+		ConsumedCapacity: q.ConsumedCapacity,
 	}
 }
 
@@ -30,5 +32,14 @@ func NewOutputFromScanOutput(s *dynamodb.ScanOutput) *Output {
 		ScannedCount:     s.ScannedCount,
 		Items:            s.Items,
 		LastEvaluatedKey: s.LastEvaluatedKey,
+		ConsumedCapacity: s.ConsumedCapacity,
 	}
 }
+
+// CountOutput represents the totals of a count-only query or scan operation across all pages.
+type CountOutput struct {
+	// Count is the number of items matching the key condition and filter.
+	Count int64
+	// ScannedCount is the number of items evaluated before applying the filter.
+	ScannedCount int64
+}
@@ -0,0 +1,125 @@
+package dynamorm
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ScanResult is an item, or an error, produced by one segment of a parallel scan.
+type ScanResult struct {
+	// Segment is the zero-based segment the item was read from.
+	Segment int
+	// Item is the raw DynamoDB item; nil when Err is set.
+	Item map[string]types.AttributeValue
+	// Err is the error that stopped the segment.
+	Err error
+
+	decoder  DecoderInterface
+	keys     KeySchema
+	registry *Registry
+}
+
+// Decode decodes the item into the provided entity.
+func (r ScanResult) Decode(e Entity) error {
+	if r.Item == nil {
+		return ErrIndexOutOfRange
+	}
+
+	decoder := r.decoder
+	if decoder == nil {
+		decoder = DefaultDecoder()
+	}
+	return decodeItem(decoder, r.keys, r.Item, e)
+}
+
+// DecodeAny decodes the item into a new entity of the type named by its TypeAttribute. See Query.DecodeAny.
+func (r ScanResult) DecodeAny() (Entity, error) {
+	if r.Item == nil {
+		return nil, ErrIndexOutOfRange
+	}
+
+	decoder := r.decoder
+	if decoder == nil {
+		decoder = DefaultDecoder()
+	}
+	return r.registry.decode(decoder, r.keys, r.Item)
+}
+
+func (s *Storage) ParallelScan(ctx context.Context, segments int, opts ...ScanOption) <-chan ScanResult {
+	return s.parallelScan(ctx, nil, segments, opts...)
+}
+
+func (s *Storage) ParallelScanGSI1(ctx context.Context, segments int, opts ...ScanOption) <-chan ScanResult {
+	return s.parallelScan(ctx, aws.String(s.keys.GSI1.Name), segments, opts...)
+}
+
+func (s *Storage) ParallelScanGSI2(ctx context.Context, segments int, opts ...ScanOption) <-chan ScanResult {
+	return s.parallelScan(ctx, aws.String(s.keys.GSI2.Name), segments, opts...)
+}
+
+func (s *Storage) parallelScan(ctx context.Context, index *string, segments int, opts ...ScanOption) <-chan ScanResult {
+	if segments < 1 {
+		segments = 1
+	}
+
+	results := make(chan ScanResult, segments)
+
+	var wg sync.WaitGroup
+	for segment := range segments {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			input := &dynamodb.ScanInput{
+				TableName:     aws.String(s.table),
+				IndexName:     index,
+				Segment:       aws.Int32(int32(segment)),
+				TotalSegments: aws.Int32(int32(segments)),
+			}
+			s.scanSegment(ctx, segment, input, results, opts...)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// scanSegment sends every item of a single segment to results, followed by the error
+// that stopped the segment, if any.
+func (s *Storage) scanSegment(ctx context.Context, segment int, input *dynamodb.ScanInput, results chan<- ScanResult, opts ...ScanOption) {
+	send := func(r ScanResult) bool {
+		r.Segment = segment
+		select {
+		case results <- r:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	query, err := s.newScan(ctx, input, opts...)
+	if err != nil {
+		send(ScanResult{Err: err})
+		return
+	}
+
+	for query.NextPage(ctx) {
+		for _, item := range query.output.Items {
+			if !send(ScanResult{Item: item, decoder: s.decoder, keys: s.keys, registry: s.registry}) {
+				return
+			}
+		}
+	}
+
+	if err = query.Error(); err != nil {
+		send(ScanResult{Err: err})
+	}
+}
@@ -0,0 +1,84 @@
+package dynamorm
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+// patchUpdate builds the update writing the given attributes of an entity, encoded like Save does,
+// along with its index keys recomputed from GSI1(), GSI2() and IndexKeys(), once the entity is validated.
+// Attributes missing from the encoded entity, e.g. omitted when empty, are removed, as are the keys
+// of the indexes the entity is not part of.
+// The key attributes and the attributes managed by the storage, such as the version, are ignored.
+func patchUpdate(e Entity, encoder EncoderInterface, keys KeySchema, registry *Registry, ttl string, fields []string) (expression.UpdateBuilder, error) {
+	var update expression.UpdateBuilder
+
+	item, err := encodeItem(e, encoder, keys, registry, ttl, nil)
+	if err != nil {
+		return update, err
+	}
+
+	managed := make(map[string]bool)
+	for _, name := range keys.attributes() {
+		managed[name] = true
+	}
+	if _, ok := Unwrap(e).(Versioned); ok {
+		managed[VersionAttribute] = true
+	}
+	if _, ok := Unwrap(e).(Timestamped); ok {
+		managed[CreatedAtAttribute] = true
+		managed[UpdatedAtAttribute] = true
+	}
+
+	for _, field := range fields {
+		if managed[field] {
+			continue
+		}
+		managed[field] = true
+
+		if v, ok := item[field]; ok {
+			update = update.Set(expression.Name(field), expression.Value(rawValue{v}))
+		} else {
+			update = update.Remove(expression.Name(field))
+		}
+	}
+
+	for _, index := range keys.indexKeys(e) {
+		values := index.values()
+		for _, name := range index.attributes() {
+			if v, ok := values[name]; ok {
+				update = update.Set(expression.Name(name), expression.Value(rawValue{v}))
+			} else {
+				update = update.Remove(expression.Name(name))
+			}
+		}
+	}
+
+	return update, nil
+}
+
+// Patch writes only the given attributes of an entity, as encoded by the configured Encoder, and
+// recomputes its index keys from entity.GSI1(), entity.GSI2() and IndexedEntity.IndexKeys(),
+// in a single UpdateItem request. The BeforeSave() hook is called on the entity beforehand.
+// Attributes missing from the encoded entity are removed. Like Update, it manages the version
+// and timestamps of the entity.
+func (s *Storage) Patch(ctx context.Context, e Entity, fields ...string) error {
+	update, err := patchUpdate(e, s.encoder, s.keys, s.registry, s.ttl, fields)
+	if err != nil {
+		return err
+	}
+
+	return s.Update(ctx, e, update)
+}
+
+// AddPatch adds an Update operation writing only the given attributes of the entity
+// to the transaction. See Storage.Patch.
+func (tx *Transaction) AddPatch(e Entity, fields ...string) error {
+	update, err := patchUpdate(e, tx.encoder, tx.keys, tx.registry, tx.ttl, fields)
+	if err != nil {
+		return err
+	}
+
+	return tx.AddUpdate(e, update)
+}
@@ -2,9 +2,11 @@ package dynamorm
 
 import (
 	"context"
-	"fmt"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 // QueryInterface provides an interface to handle DynamoDB query results.
@@ -33,6 +35,16 @@ type QueryInterface interface {
 	Reset()
 	// Decode decodes the current item into the provided interface
 	Decode(Entity) error
+	// DecodeAny decodes the current item into a new entity of the type named by its TypeAttribute,
+	// as registered with WithEntityType. Returns ErrUnknownEntityType if the type is not registered.
+	DecodeAny() (Entity, error)
+	// Cursor returns an opaque token encoding the LastEvaluatedKey of the current page,
+	// to be passed to QueryStartFrom or ScanStartFrom to resume after this page.
+	// Returns an empty string if there are no more pages.
+	Cursor() (string, error)
+	// ConsumedCapacity returns the capacity consumed by the current page, or nil
+	// unless WithConsumedCapacity is enabled.
+	ConsumedCapacity() *types.ConsumedCapacity
 }
 
 // Query implements the QueryInterface for handling DynamoDB query results.
@@ -47,6 +59,33 @@ type Query struct {
 	index   int
 	paged   bool
 	err     error
+
+	cursorSecret []byte
+	maxItems     int
+	capacity     *ConsumedCapacity
+	keys         KeySchema
+	registry     *Registry
+}
+
+// readConfig holds the settings of a get, query or scan that are not part of the DynamoDB input.
+type readConfig struct {
+	maxItems       int
+	includeDeleted bool
+	filter         *expression.ConditionBuilder
+}
+
+// configBuilder is the builder handed to get, query and scan options by the storage,
+// giving options such as QueryMaxItems access to the readConfig.
+type configBuilder struct {
+	BuilderInterface
+	cfg *readConfig
+}
+
+// WithFilter keeps the filter of an option in the readConfig, so that the storage
+// can combine it with its own conditions.
+func (b *configBuilder) WithFilter(filter expression.ConditionBuilder) BuilderInterface {
+	b.cfg.filter = &filter
+	return b.BuilderInterface
 }
 
 // NewQuery creates a new Query instance from the query input and output.
@@ -67,6 +106,7 @@ func NewQuery(client DynamoDB, query *dynamodb.QueryInput, scan *dynamodb.ScanIn
 		scan:    scan,
 		output:  output,
 		decoder: decoder,
+		keys:    DefaultKeySchema(),
 	}
 }
 
@@ -84,10 +124,7 @@ func (q *Query) First(e Entity) error {
 	}
 
 	item := q.output.Items[0]
-	if err := q.decoder.Decode(item, e); err != nil {
-		return fmt.Errorf("%w: %v", ErrEntityDecode, err)
-	}
-	return nil
+	return decodeItem(q.decoder, q.keys, item, e)
 }
 
 func (q *Query) Last(e Entity) error {
@@ -97,10 +134,7 @@ func (q *Query) Last(e Entity) error {
 	}
 
 	item := q.output.Items[length-1]
-	if err := q.decoder.Decode(item, e); err != nil {
-		return fmt.Errorf("%w: %v", ErrEntityDecode, err)
-	}
-	return nil
+	return decodeItem(q.decoder, q.keys, item, e)
 }
 
 func (q *Query) Next() bool {
@@ -115,31 +149,107 @@ func (q *Query) NextPage(ctx context.Context) bool {
 		return true
 	}
 
-	if q.output.LastEvaluatedKey == nil {
+	// A query limited by QueryMaxItems or ScanMaxItems stops after its single page
+	if q.output.LastEvaluatedKey == nil || q.maxItems > 0 {
+		return false
+	}
+
+	output, err := q.fetch(ctx, q.output.LastEvaluatedKey)
+	if err != nil {
+		q.err = err
 		return false
 	}
+	q.output = output
 
+	q.Reset()
+	return true
+}
+
+// fetch requests the page starting after the given key.
+func (q *Query) fetch(ctx context.Context, startKey map[string]types.AttributeValue) (*Output, error) {
+	var output *Output
 	if q.scan != nil {
-		q.scan.ExclusiveStartKey = q.output.LastEvaluatedKey
+		q.scan.ExclusiveStartKey = startKey
 		out, err := q.client.Scan(ctx, q.scan)
 		if err != nil {
-			q.err = NewClientError(err)
-			return false
+			return nil, NewClientError(err)
 		}
-		q.output = NewOutputFromScanOutput(out)
-
+		output = NewOutputFromScanOutput(out)
 	} else {
-		q.query.ExclusiveStartKey = q.output.LastEvaluatedKey
+		q.query.ExclusiveStartKey = startKey
 		out, err := q.client.Query(ctx, q.query)
 		if err != nil {
-			q.err = NewClientError(err)
-			return false
+			return nil, NewClientError(err)
 		}
-		q.output = NewOutputFromQueryOutput(out)
+		output = NewOutputFromQueryOutput(out)
 	}
 
-	q.Reset()
-	return true
+	recordCapacity(ctx, q.capacity, capacities(output.ConsumedCapacity)...)
+	return output, nil
+}
+
+// collect fetches pages until maxItems items are gathered or the results are exhausted,
+// and merges them into a single page. When more items were fetched, the page is truncated
+// and its LastEvaluatedKey set to the key of the last item kept, so resuming from it
+// continues exactly after that item.
+func (q *Query) collect(ctx context.Context, maxItems int) error {
+	q.maxItems = maxItems
+
+	output := q.output
+	items := output.Items
+	scanned := output.ScannedCount
+	capacity := output.ConsumedCapacity
+	for len(items) < maxItems && output.LastEvaluatedKey != nil {
+		next, err := q.fetch(ctx, output.LastEvaluatedKey)
+		if err != nil {
+			return err
+		}
+		items = append(items, next.Items...)
+		scanned += next.ScannedCount
+		if next.ConsumedCapacity != nil {
+			sum := &types.ConsumedCapacity{TableName: next.ConsumedCapacity.TableName}
+			if capacity != nil {
+				addConsumedCapacity(sum, *capacity)
+			}
+			addConsumedCapacity(sum, *next.ConsumedCapacity)
+			capacity = sum
+		}
+		output = next
+	}
+
+	lastKey := output.LastEvaluatedKey
+	if len(items) > maxItems {
+		items = items[:maxItems]
+		lastKey = q.itemKey(items[maxItems-1])
+	}
+
+	q.output = &Output{
+		Count:            int32(len(items)),
+		ScannedCount:     scanned,
+		Items:            items,
+		LastEvaluatedKey: lastKey,
+		ConsumedCapacity: capacity,
+	}
+	return nil
+}
+
+// itemKey extracts the primary key of an item, along with the index key when reading a GSI,
+// in the form DynamoDB expects for an ExclusiveStartKey.
+func (q *Query) itemKey(item map[string]types.AttributeValue) map[string]types.AttributeValue {
+	names := []string{q.keys.PK, q.keys.SK}
+	if _, name := q.target(); name != "" {
+		if index, ok := q.keys.index(name); ok {
+			names = append(names, index.attributes()...)
+		}
+	}
+
+	key := make(map[string]types.AttributeValue, len(names))
+	for _, name := range names {
+		if v, ok := item[name]; ok {
+			key[name] = v
+		}
+	}
+	return key
 }
 
 func (q *Query) Reset() {
@@ -152,13 +262,38 @@ func (q *Query) Decode(e Entity) error {
 		return ErrIndexOutOfRange
 	}
 
-	if err := q.decoder.Decode(q.output.Items[q.index-1], e); err != nil {
-		return fmt.Errorf("%w: %v", ErrEntityDecode, err)
+	return decodeItem(q.decoder, q.keys, q.output.Items[q.index-1], e)
+}
+
+func (q *Query) DecodeAny() (Entity, error) {
+	if q.index <= 0 || q.index > len(q.output.Items) {
+		return nil, ErrIndexOutOfRange
 	}
 
-	return nil
+	return q.registry.decode(q.decoder, q.keys, q.output.Items[q.index-1])
 }
 
 func (q *Query) Error() error {
 	return q.err
 }
+
+func (q *Query) Cursor() (string, error) {
+	if len(q.output.LastEvaluatedKey) == 0 {
+		return "", nil
+	}
+
+	table, index := q.target()
+	return encodeCursor(table, index, q.output.LastEvaluatedKey, q.cursorSecret)
+}
+
+func (q *Query) ConsumedCapacity() *types.ConsumedCapacity {
+	return q.output.ConsumedCapacity
+}
+
+// target returns the table and index names the query or scan reads from.
+func (q *Query) target() (string, string) {
+	if q.scan != nil {
+		return aws.ToString(q.scan.TableName), aws.ToString(q.scan.IndexName)
+	}
+	return aws.ToString(q.query.TableName), aws.ToString(q.query.IndexName)
+}
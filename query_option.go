@@ -4,6 +4,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 // QueryOption customizes the DynamoDB QueryInput built by Storage.Query, Storage.QueryGSI1,
@@ -62,3 +63,53 @@ func QueryConsistent(consistent bool) QueryOption {
 		return nil
 	}
 }
+
+// QuerySelectCount makes the Query operation return only the number of matching items
+// instead of the items themselves, by setting Select to COUNT on the QueryInput.
+// It cannot be combined with QueryAttribute.
+func QuerySelectCount() QueryOption {
+	return func(input *dynamodb.QueryInput, _ BuilderInterface) BuilderInterface {
+		input.Select = types.SelectCount
+		return nil
+	}
+}
+
+// QueryMaxItems makes the Query operation fetch pages until n matching items are collected, or
+// the results are exhausted, and return them as a single page. Unlike QueryLimit, which bounds the
+// number of items evaluated per request, it bounds the number of items returned, so filters no longer
+// yield empty or short pages. NextPage then stops, and Cursor resumes right after the last item
+// returned, which requires the key attributes to be part of any projection. It is ignored by CountQuery.
+func QueryMaxItems(n int) QueryOption {
+	return func(_ *dynamodb.QueryInput, builder BuilderInterface) BuilderInterface {
+		if b, ok := builder.(*configBuilder); ok && n > 0 {
+			b.cfg.maxItems = n
+		}
+		return nil
+	}
+}
+
+// QueryStartFrom resumes a Query operation right after the position encoded in a cursor
+// returned by Query.Cursor. An empty cursor starts from the beginning. The cursor is validated
+// by the storage, which returns ErrInvalidCursor if it is malformed, was tampered with, or was
+// issued for another table, index or partition.
+func QueryStartFrom(cursor string) QueryOption {
+	return func(input *dynamodb.QueryInput, _ BuilderInterface) BuilderInterface {
+		if cursor != "" {
+			input.ExclusiveStartKey = map[string]types.AttributeValue{
+				cursorAttribute: &types.AttributeValueMemberS{Value: cursor},
+			}
+		}
+		return nil
+	}
+}
+
+// QueryIncludeDeleted makes the Query operation return items soft-deleted by Storage.SoftRemove,
+// which are otherwise left out of the results when soft delete is enabled with WithSoftDelete.
+func QueryIncludeDeleted() QueryOption {
+	return func(_ *dynamodb.QueryInput, builder BuilderInterface) BuilderInterface {
+		if b, ok := builder.(*configBuilder); ok {
+			b.cfg.includeDeleted = true
+		}
+		return nil
+	}
+}
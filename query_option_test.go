@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/require"
 	"github.com/vpriem/dynamorm"
 	"go.uber.org/mock/gomock"
@@ -81,3 +82,39 @@ func TestQueryAttribute(t *testing.T) {
 	nextBuilder = dynamorm.QueryAttribute("Attr1", "Attr2")(nil, builder)
 	require.Equal(t, builder, nextBuilder)
 }
+
+func TestQuerySelectCount(t *testing.T) {
+	input := &dynamodb.QueryInput{}
+
+	nextBuilder := dynamorm.QuerySelectCount()(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Equal(t, types.SelectCount, input.Select)
+}
+
+func TestQueryMaxItems(t *testing.T) {
+	input := &dynamodb.QueryInput{}
+
+	nextBuilder := dynamorm.QueryMaxItems(10)(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Equal(t, &dynamodb.QueryInput{}, input)
+}
+
+func TestQueryStartFrom(t *testing.T) {
+	input := &dynamodb.QueryInput{}
+
+	nextBuilder := dynamorm.QueryStartFrom("")(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Nil(t, input.ExclusiveStartKey)
+
+	nextBuilder = dynamorm.QueryStartFrom("cursor")(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Len(t, input.ExclusiveStartKey, 1)
+}
+
+func TestQueryIncludeDeleted(t *testing.T) {
+	input := &dynamodb.QueryInput{}
+
+	nextBuilder := dynamorm.QueryIncludeDeleted()(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Equal(t, &dynamodb.QueryInput{}, input)
+}
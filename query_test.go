@@ -411,3 +411,41 @@ func TestScanPagination(t *testing.T) {
 		}, emails)
 	})
 }
+
+func TestQueryCursor(t *testing.T) {
+	t.Run("should return empty cursor on last page", func(t *testing.T) {
+		q := dynamorm.NewQuery(nil, nil, nil, nil, nil)
+		cursor, err := q.Cursor()
+		require.NoError(t, err)
+		require.Empty(t, cursor)
+	})
+
+	t.Run("should return cursor", func(t *testing.T) {
+		output := &dynamorm.Output{
+			LastEvaluatedKey: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberS{Value: "PK#1"},
+				"SK": &types.AttributeValueMemberS{Value: "SK#1"},
+			},
+		}
+		input := &dynamodb.QueryInput{TableName: aws.String("TestTable")}
+		q := dynamorm.NewQuery(nil, input, nil, output, nil)
+
+		cursor, err := q.Cursor()
+		require.NoError(t, err)
+		require.NotEmpty(t, cursor)
+		require.NotContains(t, cursor, "PK#1")
+	})
+
+	t.Run("should return ErrInvalidCursor for unsupported key type", func(t *testing.T) {
+		output := &dynamorm.Output{
+			LastEvaluatedKey: map[string]types.AttributeValue{
+				"PK": &types.AttributeValueMemberBOOL{Value: true},
+			},
+		}
+		q := dynamorm.NewQuery(nil, nil, &dynamodb.ScanInput{}, output, nil)
+
+		cursor, err := q.Cursor()
+		require.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+		require.Empty(t, cursor)
+	})
+}
@@ -0,0 +1,94 @@
+package dynamorm
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TypeAttribute is the discriminator attribute holding the type name of the entities
+// registered with WithEntityType, written on save and read by Query.DecodeAny.
+const TypeAttribute = "_type"
+
+// Registry maps type names to entity types, so that items of different types returned
+// by a single query can be decoded into entities of the right concrete type.
+type Registry struct {
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		types: make(map[string]reflect.Type),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// Register registers the type of the entity, which must be a pointer to a struct, or a struct
+// adapted by Keyed, under the given name. Entities of other kinds and empty names are ignored.
+// Registering a name again replaces its type.
+func (r *Registry) Register(name string, e Entity) {
+	t := reflect.TypeOf(Unwrap(e))
+	if name == "" || t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return
+	}
+
+	if previous, ok := r.types[name]; ok {
+		delete(r.names, previous)
+	}
+	r.types[name] = t
+	r.names[t] = name
+}
+
+// Name returns the name the type of the entity is registered under.
+func (r *Registry) Name(e Entity) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	name, ok := r.names[reflect.TypeOf(Unwrap(e))]
+	return name, ok
+}
+
+// New allocates a new entity of the type registered under the given name. Structs that do not
+// implement Entity are adapted with Keyed.
+func (r *Registry) New(name string) (Entity, bool) {
+	if r == nil {
+		return nil, false
+	}
+	t, ok := r.types[name]
+	if !ok {
+		return nil, false
+	}
+	v := reflect.New(t.Elem()).Interface()
+	if e, ok := v.(Entity); ok {
+		return e, true
+	}
+	return Keyed(v), true
+}
+
+// setType adds the type name of a registered entity to its item.
+func (r *Registry) setType(e Entity, item map[string]types.AttributeValue) {
+	if name, ok := r.Name(e); ok {
+		item[TypeAttribute] = &types.AttributeValueMemberS{Value: name}
+	}
+}
+
+// decode decodes an item into a new entity of the type named by its TypeAttribute.
+func (r *Registry) decode(decoder DecoderInterface, keys KeySchema, item map[string]types.AttributeValue) (Entity, error) {
+	v, ok := item[TypeAttribute].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing %s attribute", ErrUnknownEntityType, TypeAttribute)
+	}
+
+	e, ok := r.New(v.Value)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEntityType, v.Value)
+	}
+
+	if err := decodeItem(decoder, keys, item, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 // ScanOption customizes the DynamoDB ScanInput request built by Storage.Scan.
@@ -40,3 +41,53 @@ func ScanAttribute(attrs ...string) ScanOption {
 		return builder.WithProjection(proj)
 	}
 }
+
+// ScanSelectCount makes the Scan operation return only the number of matching items
+// instead of the items themselves, by setting Select to COUNT on the ScanInput.
+// It cannot be combined with ScanAttribute.
+func ScanSelectCount() ScanOption {
+	return func(input *dynamodb.ScanInput, _ BuilderInterface) BuilderInterface {
+		input.Select = types.SelectCount
+		return nil
+	}
+}
+
+// ScanMaxItems makes the Scan operation fetch pages until n matching items are collected, or
+// the results are exhausted, and return them as a single page. Unlike ScanLimit, which bounds the
+// number of items evaluated per request, it bounds the number of items returned, so filters no longer
+// yield empty or short pages. NextPage then stops, and Cursor resumes right after the last item
+// returned, which requires the key attributes to be part of any projection. It is ignored by CountScan.
+func ScanMaxItems(n int) ScanOption {
+	return func(_ *dynamodb.ScanInput, builder BuilderInterface) BuilderInterface {
+		if b, ok := builder.(*configBuilder); ok && n > 0 {
+			b.cfg.maxItems = n
+		}
+		return nil
+	}
+}
+
+// ScanStartFrom resumes a Scan operation right after the position encoded in a cursor
+// returned by Query.Cursor. An empty cursor starts from the beginning. The cursor is validated
+// by the storage, which returns ErrInvalidCursor if it is malformed, was tampered with, or was
+// issued for another table or index.
+func ScanStartFrom(cursor string) ScanOption {
+	return func(input *dynamodb.ScanInput, _ BuilderInterface) BuilderInterface {
+		if cursor != "" {
+			input.ExclusiveStartKey = map[string]types.AttributeValue{
+				cursorAttribute: &types.AttributeValueMemberS{Value: cursor},
+			}
+		}
+		return nil
+	}
+}
+
+// ScanIncludeDeleted makes the Scan operation return items soft-deleted by Storage.SoftRemove,
+// which are otherwise left out of the results when soft delete is enabled with WithSoftDelete.
+func ScanIncludeDeleted() ScanOption {
+	return func(_ *dynamodb.ScanInput, builder BuilderInterface) BuilderInterface {
+		if b, ok := builder.(*configBuilder); ok {
+			b.cfg.includeDeleted = true
+		}
+		return nil
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/require"
 	"github.com/vpriem/dynamorm"
 	"go.uber.org/mock/gomock"
@@ -50,3 +51,39 @@ func TestScanAttribute(t *testing.T) {
 	nextBuilder = dynamorm.ScanAttribute("Attr1", "Attr2")(nil, builder)
 	require.Equal(t, builder, nextBuilder)
 }
+
+func TestScanSelectCount(t *testing.T) {
+	input := &dynamodb.ScanInput{}
+
+	nextBuilder := dynamorm.ScanSelectCount()(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Equal(t, types.SelectCount, input.Select)
+}
+
+func TestScanMaxItems(t *testing.T) {
+	input := &dynamodb.ScanInput{}
+
+	nextBuilder := dynamorm.ScanMaxItems(10)(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Equal(t, &dynamodb.ScanInput{}, input)
+}
+
+func TestScanStartFrom(t *testing.T) {
+	input := &dynamodb.ScanInput{}
+
+	nextBuilder := dynamorm.ScanStartFrom("")(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Nil(t, input.ExclusiveStartKey)
+
+	nextBuilder = dynamorm.ScanStartFrom("cursor")(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Len(t, input.ExclusiveStartKey, 1)
+}
+
+func TestScanIncludeDeleted(t *testing.T) {
+	input := &dynamodb.ScanInput{}
+
+	nextBuilder := dynamorm.ScanIncludeDeleted()(input, nil)
+	require.Nil(t, nextBuilder)
+	require.Equal(t, &dynamodb.ScanInput{}, input)
+}
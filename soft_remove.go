@@ -0,0 +1,96 @@
+package dynamorm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DeletedAtAttribute is the default attribute marking an item as soft-deleted by Storage.SoftRemove
+// once soft delete is enabled with WithSoftDelete.
+const DeletedAtAttribute = "DeletedAt"
+
+// deleted reports whether an item is soft-deleted. Items are never soft-deleted when attribute is empty.
+func deleted(item map[string]types.AttributeValue, attribute string) bool {
+	if attribute == "" {
+		return false
+	}
+	v, ok := item[attribute]
+	if !ok {
+		return false
+	}
+	_, null := v.(*types.AttributeValueMemberNULL)
+	return !null
+}
+
+// notDeleted returns the filter condition leaving out soft-deleted items.
+func notDeleted(attribute string) expression.ConditionBuilder {
+	deletedAt := expression.Name(attribute)
+	return deletedAt.AttributeNotExists().Or(deletedAt.AttributeType(expression.Null))
+}
+
+// SoftRemove marks an existing entity as deleted instead of deleting it: it sets the attribute given to
+// WithSoftDelete to the current time and removes the index key attributes, so the item drops out of the secondary indexes.
+// Get, BatchGet, Query and Scan then treat the item as missing unless GetIncludeDeleted, QueryIncludeDeleted
+// or ScanIncludeDeleted is passed. Like Remove, it calls the BeforeRemove() and AfterRemove() hooks
+// around the update. Returns ErrEntityNotFound if the item doesn't exist, and ErrSoftDeleteDisabled if
+// the storage was created without WithSoftDelete.
+func (s *Storage) SoftRemove(ctx context.Context, e Entity) error {
+	if s.deletedAt == "" {
+		return ErrSoftDeleteDisabled
+	}
+	if err := beforeRemove(e); err != nil {
+		return err
+	}
+
+	update := expression.Set(expression.Name(s.deletedAt), expression.Value(s.clock()))
+	for _, index := range s.keys.indexes() {
+		for _, name := range index.attributes() {
+			update = update.Remove(expression.Name(name))
+		}
+	}
+
+	if err := s.updateExisting(ctx, e, update); err != nil {
+		return err
+	}
+
+	return afterRemove(e)
+}
+
+// Restore undoes SoftRemove: it removes the soft delete attribute and recomputes the index key attributes
+// from entity.GSI1(), entity.GSI2() and IndexedEntity.IndexKeys(), so the entity is expected
+// to be loaded beforehand, e.g. with Get and GetIncludeDeleted.
+// Returns ErrEntityNotFound if the item doesn't exist, and ErrSoftDeleteDisabled if the storage
+// was created without WithSoftDelete.
+func (s *Storage) Restore(ctx context.Context, e Entity) error {
+	if s.deletedAt == "" {
+		return ErrSoftDeleteDisabled
+	}
+
+	update := expression.Remove(expression.Name(s.deletedAt))
+	for _, index := range s.keys.indexKeys(e) {
+		values := index.values()
+		for _, name := range index.attributes() {
+			if v, ok := values[name]; ok {
+				update = update.Set(expression.Name(name), expression.Value(rawValue{v}))
+			}
+		}
+	}
+
+	return s.updateExisting(ctx, e, update)
+}
+
+// updateExisting updates an entity on condition that its item exists,
+// returning ErrEntityNotFound otherwise.
+func (s *Storage) updateExisting(ctx context.Context, e Entity, update expression.UpdateBuilder, opts ...UpdateOption) error {
+	opts = append(opts, UpdateCondition(expression.AttributeExists(expression.Name(s.keys.PK))))
+	err := s.Update(ctx, e, update, opts...)
+
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return ErrEntityNotFound
+	}
+	return err
+}
@@ -13,7 +13,12 @@ package dynamorm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
@@ -27,11 +32,20 @@ type StorageInterface interface {
 	// Get retrieves an entity from DynamoDB by its partition key (PK) and sort key (SK).
 	// It calls entity.PkSk() to determine how to query the entity.
 	// Optional Get options can customize the underlying GetItemInput request.
-	// Returns ErrEntityNotFound if the item doesn't exist in the table.
+	// Returns ErrEntityNotFound if the item doesn't exist in the table, has expired, or was soft-deleted.
 	Get(context.Context, Entity, ...GetOption) error
 
+	// BatchGet retrieves one or more entities from DynamoDB using BatchGetItem.
+	// It uses PkSk() for each entity to compute the key and decodes each returned item
+	// into the entity with the matching PK/SK. Unprocessed keys are re-submitted with backoff.
+	// Returns a NotFoundError (matching ErrEntityNotFound) listing the entities that do not exist, have expired
+	// or were soft-deleted, or a BatchError (matching ErrBatch) listing the entities that could not be retrieved.
+	// Note: DynamoDB limits BatchGetItem to 100 keys per request; larger inputs are chunked.
+	BatchGet(context.Context, ...Entity) error
+
 	// Query performs a query operation on the table using the partition key (PK).
 	// An optional SK condition can be provided to refine the query, as well as additional filters.
+	// Returns ErrNoSortKey when given an SK condition while the table has no sort key.
 	// It returns a QueryInterface for iterating through the results.
 	Query(context.Context, string, SkCondition, ...QueryOption) (QueryInterface, error)
 
@@ -45,6 +59,20 @@ type StorageInterface interface {
 	// It returns a QueryInterface for iterating through the results.
 	QueryGSI2(context.Context, string, SkCondition, ...QueryOption) (QueryInterface, error)
 
+	// QueryIndex performs a query operation on a secondary index registered with WithIndexes,
+	// or on GSI1 or GSI2. For local secondary indexes, the partition key is the one of the table.
+	// Returns ErrUnknownIndex if the index is not registered.
+	QueryIndex(context.Context, string, string, SkCondition, ...QueryOption) (QueryInterface, error)
+
+	// LoadCollection queries all the items of a partition and decodes each of them
+	// into the target of the first binding it matches, e.g. BindPrefix("ORDER#", &orders).
+	// Returns a NotFoundError listing the entity targets that matched no item.
+	LoadCollection(context.Context, string, []Binding, ...QueryOption) error
+
+	// CountQuery counts the items of a partition matching an optional SK condition and filters.
+	// It sends Select=COUNT and follows LastEvaluatedKey across all pages to return the totals.
+	CountQuery(context.Context, string, SkCondition, ...QueryOption) (CountOutput, error)
+
 	// Scan performs a scan operation on the table.
 	// Optional ScanOption(s) can customize the underlying ScanInput (e.g., limit, filter, projection).
 	// It returns a QueryInterface for iterating through the results.
@@ -60,6 +88,30 @@ type StorageInterface interface {
 	// It returns a QueryInterface for iterating through the results.
 	ScanGSI2(context.Context, ...ScanOption) (QueryInterface, error)
 
+	// ScanIndex performs a scan operation on a secondary index registered with WithIndexes,
+	// or on GSI1 or GSI2. Returns ErrUnknownIndex if the index is not registered.
+	ScanIndex(context.Context, string, ...ScanOption) (QueryInterface, error)
+
+	// CountScan counts the items of the table matching optional filters.
+	// It sends Select=COUNT and follows LastEvaluatedKey across all pages to return the totals.
+	CountScan(context.Context, ...ScanOption) (CountOutput, error)
+
+	// ParallelScan scans the table in the given number of segments concurrently, one goroutine per segment.
+	// Optional ScanOption(s) are applied to every segment.
+	// Items of all segments are merged into the returned channel, which is closed once every segment
+	// is exhausted, has failed, or the context is canceled. Errors are reported per segment.
+	// Callers must either drain the channel or cancel the context: segments block until their results
+	// are received, so a channel abandoned part-way leaks their goroutines.
+	ParallelScan(context.Context, int, ...ScanOption) <-chan ScanResult
+
+	// ParallelScanGSI1 scans the Global Secondary Index 1 in the given number of segments concurrently.
+	// See ParallelScan.
+	ParallelScanGSI1(context.Context, int, ...ScanOption) <-chan ScanResult
+
+	// ParallelScanGSI2 scans the Global Secondary Index 2 in the given number of segments concurrently.
+	// See ParallelScan.
+	ParallelScanGSI2(context.Context, int, ...ScanOption) <-chan ScanResult
+
 	// Save persists a single entity to DynamoDB.
 	// It calls entity.PkSk() to populate PK and SK, and entity.GSI1() and entity.GSI2()
 	// to populate GSI PK and SK. The BeforeSave() hook is called on the entity before saving.
@@ -68,23 +120,56 @@ type StorageInterface interface {
 
 	// BatchSave persists one or more entities to DynamoDB using BatchWriteItem.
 	// It calls BeforeSave() and uses PkSk(), GSI1(), and GSI2() for each entity.
-	// Note: DynamoDB limits BatchWriteItem to 25 items per request; larger inputs are chunked.
+	// Unprocessed items are re-submitted with backoff; a BatchError (matching ErrBatch)
+	// lists the entities that could not be written.
+	// Note: DynamoDB limits BatchWriteItem to 25 items per request; larger inputs are chunked
+	// and sent by up to WithBatchConcurrency workers in parallel.
 	BatchSave(context.Context, ...Entity) error
 
 	// BatchRemove deletes one or more entities from DynamoDB using BatchWriteItem with DeleteRequests.
 	// It uses PkSk() for each entity to compute the key.
-	// Note: DynamoDB limits BatchWriteItem to 25 items per request; larger inputs are chunked.
+	// Unprocessed items are re-submitted with backoff; a BatchError (matching ErrBatch)
+	// lists the entities that could not be removed.
+	// Note: DynamoDB limits BatchWriteItem to 25 items per request; larger inputs are chunked
+	// and sent by up to WithBatchConcurrency workers in parallel.
 	BatchRemove(context.Context, ...Entity) error
 
 	// Update applies one or more update operations to an existing item identified by its PK/SK.
 	// If the operation returns attributes, they are decoded into the provided entity; otherwise, the entity is left unchanged.
 	Update(context.Context, Entity, expression.UpdateBuilder, ...UpdateOption) error
 
+	// Patch writes only the named attributes of an entity, encoded with the configured Encoder,
+	// and recomputes its index keys from entity.GSI1(), entity.GSI2() and IndexedEntity.IndexKeys(),
+	// in a single UpdateItem request.
+	Patch(context.Context, Entity, ...string) error
+
+	// SaveChanges saves a Tracked entity by writing only the attributes that changed since
+	// it was loaded or saved, in a single UpdateItem request. Other entities are saved with Save.
+	SaveChanges(context.Context, Entity) error
+
 	// Remove deletes an entity from DynamoDB by its PK/SK.
 	// It calls entity.PkSk() to determine how to delete the entity.
 	// Returns an error if the operation fails.
 	Remove(context.Context, Entity, ...RemoveOption) error
 
+	// SoftRemove marks an entity as deleted by setting the attribute given to WithSoftDelete and removing
+	// its index keys, so that reads treat it as missing unless an IncludeDeleted option is passed.
+	// Returns ErrEntityNotFound if the item doesn't exist, and ErrSoftDeleteDisabled without WithSoftDelete.
+	SoftRemove(context.Context, Entity) error
+
+	// Restore undoes SoftRemove by removing the soft delete attribute and recomputing the index keys
+	// from entity.GSI1(), entity.GSI2() and IndexedEntity.IndexKeys().
+	// Returns ErrEntityNotFound if the item doesn't exist, and ErrSoftDeleteDisabled without WithSoftDelete.
+	Restore(context.Context, Entity) error
+
+	// EnableTTL enables Time to Live on the table, using the attribute given to WithTTL as the expiry attribute
+	// of Expirable entities.
+	EnableTTL(context.Context) error
+
+	// ConsumedCapacity returns the counter accumulating the capacity consumed by all operations
+	// of the storage, including transactions and pagination. It requires WithConsumedCapacity.
+	ConsumedCapacity() *ConsumedCapacity
+
 	// Transaction creates a new Transaction to batch multiple write operations
 	// (put, update, delete) and execute them atomically.
 	// The returned transaction uses the same table as the storage instance.
@@ -93,11 +178,22 @@ type StorageInterface interface {
 
 // Storage implements the StorageInterface for DynamoDB operations.
 type Storage struct {
-	table      string           // DynamoDB table name
-	encoder    EncoderInterface // Encoder for marshaling Go structs to DynamoDB items
-	decoder    DecoderInterface // Decoder for unmarshaling DynamoDB items to Go structs
-	newBuilder CreateBuilder    // BuilderInterface factory
-	client     DynamoDB         // DynamoDB client
+	table        string                       // DynamoDB table name
+	encoder      EncoderInterface             // Encoder for marshaling Go structs to DynamoDB items
+	decoder      DecoderInterface             // Decoder for unmarshaling DynamoDB items to Go structs
+	newBuilder   CreateBuilder                // BuilderInterface factory
+	client       DynamoDB                     // DynamoDB client
+	batchRetries int                          // Maximum number of re-submissions of unprocessed batch items
+	batchDelay   time.Duration                // Base delay of the exponential backoff between batch retries
+	batchWorkers int                          // Maximum number of concurrent BatchWriteItem requests
+	cursorSecret []byte                       // Secret used to sign and verify pagination cursors
+	capacityMode types.ReturnConsumedCapacity // Level of consumed capacity requested on every operation
+	capacity     *ConsumedCapacity            // Capacity consumed by all operations
+	clock        func() time.Time             // Clock of the timestamps and expiry of entities
+	keys         KeySchema                    // Attribute and index names of the keys
+	registry     *Registry                    // Entity types decoded by Query.DecodeAny
+	ttl          string                       // Attribute of the expiry of Expirable entities, empty if disabled
+	deletedAt    string                       // Attribute marking soft-deleted items, empty if disabled
 }
 
 // NewStorage creates a new Storage instance with the specified table name and DynamoDB client.
@@ -119,44 +215,57 @@ func NewStorage(table string, client DynamoDB, optFns ...Option) *Storage {
 		optFn(cfg)
 	}
 
-	return &Storage{table, cfg.Encoder, cfg.Decoder, cfg.NewBuilder, client}
+	return &Storage{
+		table:        table,
+		encoder:      cfg.Encoder,
+		decoder:      cfg.Decoder,
+		newBuilder:   cfg.NewBuilder,
+		client:       client,
+		batchRetries: cfg.BatchRetries,
+		batchDelay:   cfg.BatchRetryDelay,
+		batchWorkers: cfg.BatchConcurrency,
+		cursorSecret: cfg.CursorSecret,
+		capacityMode: cfg.ConsumedCapacity,
+		capacity:     &ConsumedCapacity{},
+		clock:        cfg.Clock,
+		keys:         cfg.KeySchema,
+		registry:     cfg.Registry,
+		ttl:          cfg.TTLAttribute,
+		deletedAt:    cfg.DeletedAtAttribute,
+	}
 }
 
 func (s *Storage) createItem(e Entity) (map[string]types.AttributeValue, error) {
+	return encodeItem(e, s.encoder, s.keys, s.registry, s.ttl, s.clock)
+}
+
+// encodeItem calls BeforeSave(), validates an entity and encodes it into an item along with its keys,
+// type and expiry under the ttl attribute, if any. The timestamps of Timestamped entities are set beforehand,
+// unless clock is nil. It is shared by Storage and Transaction so that every write encodes entities the same way.
+func encodeItem(e Entity, encoder EncoderInterface, keys KeySchema, registry *Registry, ttl string, clock func() time.Time) (map[string]types.AttributeValue, error) {
 	if err := e.BeforeSave(); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrEntityBeforeSave, err)
 	}
-
-	pk, sk := e.PkSk()
-	if pk == "" {
-		return nil, ErrEntityPkNotSet
-	}
-	if sk == "" {
-		return nil, ErrEntitySkNotSet
+	if err := validate(e); err != nil {
+		return nil, err
 	}
 
-	item, err := s.encoder.Encode(e)
+	pk, sk, err := keys.entityKey(e)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %v", ErrEntityEncode, err)
+		return nil, err
 	}
-	item["PK"] = &types.AttributeValueMemberS{Value: pk}
-	item["SK"] = &types.AttributeValueMemberS{Value: sk}
 
-	pk, sk = e.GSI1()
-	if pk != "" {
-		item["GSI1PK"] = &types.AttributeValueMemberS{Value: pk}
-		if sk != "" {
-			item["GSI1SK"] = &types.AttributeValueMemberS{Value: sk}
-		}
+	if clock != nil {
+		touch(e, clock())
 	}
 
-	pk, sk = e.GSI2()
-	if pk != "" {
-		item["GSI2PK"] = &types.AttributeValueMemberS{Value: pk}
-		if sk != "" {
-			item["GSI2SK"] = &types.AttributeValueMemberS{Value: sk}
-		}
+	item, err := encoder.Encode(Unwrap(e))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEntityEncode, err)
 	}
+	keys.setKeys(item, e, pk, sk)
+	registry.setType(e, item)
+	expiry(e, item, ttl)
 
 	return item, nil
 }
@@ -168,15 +277,24 @@ func (s *Storage) Save(ctx context.Context, e Entity, opts ...SaveOption) error
 	}
 
 	input := &dynamodb.PutItemInput{
-		TableName: aws.String(s.table),
-		Item:      item,
+		TableName:              aws.String(s.table),
+		Item:                   item,
+		ReturnConsumedCapacity: s.capacityMode,
 	}
 
 	builder := s.newBuilder()
 	var nextBuilder BuilderInterface
+
+	lock := lockVersion(e, true, s.keys.PK)
+	if lock.conditional() {
+		item[VersionAttribute] = lock.next()
+		input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+		nextBuilder = builder.WithCondition(*lock.condition)
+	}
+
 	for _, apply := range opts {
 		if apply != nil {
-			if b := apply(input, builder); b != nil {
+			if b := apply(input, lock.wrap(builder)); b != nil {
 				nextBuilder = b
 			}
 		}
@@ -191,11 +309,15 @@ func (s *Storage) Save(ctx context.Context, e Entity, opts ...SaveOption) error
 		input.ExpressionAttributeValues = expr.Values()
 	}
 
-	_, err = s.client.PutItem(ctx, input)
+	output, err := s.client.PutItem(ctx, input)
 	if err != nil {
-		return NewClientError(err)
+		return lock.clientError(err)
 	}
-	return nil
+	recordCapacity(ctx, s.capacity, capacities(output.ConsumedCapacity)...)
+	lock.commit()
+	snapshot(e, item)
+
+	return afterSave(e)
 }
 
 func (s *Storage) BatchSave(ctx context.Context, entities ...Entity) error {
@@ -218,31 +340,27 @@ func (s *Storage) BatchSave(ctx context.Context, entities ...Entity) error {
 		})
 	}
 
-	return s.batchWrite(ctx, batches)
+	return s.batchWrite(ctx, batches, entities, afterSave)
 }
 
 func (s *Storage) Get(ctx context.Context, e Entity, opts ...GetOption) error {
-	pk, sk := e.PkSk()
-	if pk == "" {
-		return ErrEntityPkNotSet
-	}
-	if sk == "" {
-		return ErrEntitySkNotSet
+	pk, sk, err := s.keys.entityKey(e)
+	if err != nil {
+		return err
 	}
 
 	input := &dynamodb.GetItemInput{
-		TableName: aws.String(s.table),
-		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: pk},
-			"SK": &types.AttributeValueMemberS{Value: sk},
-		},
+		TableName:              aws.String(s.table),
+		Key:                    s.keys.key(pk, sk),
+		ReturnConsumedCapacity: s.capacityMode,
 	}
 
 	builder := s.newBuilder()
 	var nextBuilder BuilderInterface
+	cfg := &readConfig{}
 	for _, apply := range opts {
 		if apply != nil {
-			if b := apply(input, builder); b != nil {
+			if b := apply(input, &configBuilder{builder, cfg}); b != nil {
 				nextBuilder = b
 			}
 		}
@@ -260,13 +378,131 @@ func (s *Storage) Get(ctx context.Context, e Entity, opts ...GetOption) error {
 	if err != nil {
 		return NewClientError(err)
 	}
+	recordCapacity(ctx, s.capacity, capacities(output.ConsumedCapacity)...)
 
-	if output.Item == nil {
+	if output.Item == nil || expired(output.Item, s.ttl, s.clock()) || (!cfg.includeDeleted && deleted(output.Item, s.deletedAt)) {
 		return ErrEntityNotFound
 	}
 
-	if err = s.decoder.Decode(output.Item, e); err != nil {
-		return fmt.Errorf("%w: %v", ErrEntityDecode, err)
+	return decodeItem(s.decoder, s.keys, output.Item, e)
+}
+
+func (s *Storage) BatchGet(ctx context.Context, entities ...Entity) error {
+	if len(entities) == 0 {
+		return nil
+	}
+
+	keys := make([]map[string]types.AttributeValue, 0, len(entities))
+	entityKeys := make([][2]string, len(entities))
+	pending := make(map[[2]string][]Entity, len(entities))
+
+	for i, e := range entities {
+		pk, sk, err := s.keys.entityKey(e)
+		if err != nil {
+			return err
+		}
+
+		// DynamoDB rejects duplicate keys, so the same item is requested only once
+		// and decoded into every entity sharing its key. Keys are compared like the ones
+		// of the returned items, so that number keys match whatever their formatting.
+		key := s.keys.keys(s.keys.key(pk, sk))
+		entityKeys[i] = key
+		if _, ok := pending[key]; !ok {
+			keys = append(keys, s.keys.key(pk, sk))
+		}
+		pending[key] = append(pending[key], e)
+	}
+
+	// remaining returns, in input order, the entities whose key matches.
+	remaining := func(match func([2]string) bool) []Entity {
+		list := make([]Entity, 0, len(pending))
+		for i, e := range entities {
+			if match(entityKeys[i]) {
+				list = append(list, e)
+			}
+		}
+		return list
+	}
+	isPending := func(key [2]string) bool {
+		_, ok := pending[key]
+		return ok
+	}
+
+	const batchSize = 100
+
+	unprocessed := make(map[[2]string]bool)
+
+	for i := 0; i < len(keys); i += batchSize {
+		end := i + batchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		batch := keys[i:end]
+		for attempt := 0; len(batch) > 0 && attempt <= s.batchRetries; attempt++ {
+			if attempt > 0 {
+				if err := s.backoff(ctx, attempt); err != nil {
+					return NewBatchError(remaining(isPending), err)
+				}
+			}
+
+			input := &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{
+					s.table: {Keys: batch},
+				},
+				ReturnConsumedCapacity: s.capacityMode,
+			}
+
+			output, err := s.client.BatchGetItem(ctx, input)
+			if err != nil {
+				return NewBatchError(remaining(isPending), NewClientError(err))
+			}
+			recordCapacity(ctx, s.capacity, output.ConsumedCapacity...)
+
+			for _, item := range output.Responses[s.table] {
+				// Expired and soft-deleted items stay pending, so they are reported as not found like with Get
+				if expired(item, s.ttl, s.clock()) || deleted(item, s.deletedAt) {
+					continue
+				}
+				key := s.keys.keys(item)
+				for _, e := range pending[key] {
+					if err = decodeItem(s.decoder, s.keys, item, e); err != nil {
+						return err
+					}
+				}
+				delete(pending, key)
+			}
+
+			batch = output.UnprocessedKeys[s.table].Keys
+		}
+
+		for _, key := range batch {
+			unprocessed[s.keys.keys(key)] = true
+		}
+	}
+
+	if len(unprocessed) > 0 {
+		var notFound error
+		if list := remaining(func(key [2]string) bool { return isPending(key) && !unprocessed[key] }); len(list) > 0 {
+			notFound = NewNotFoundError(list)
+		}
+
+		batchErr := &BatchError{}
+		for i, e := range entities {
+			if key := entityKeys[i]; isPending(key) {
+				batchErr.Entities = append(batchErr.Entities, e)
+				if unprocessed[key] {
+					batchErr.Errors = append(batchErr.Errors, nil)
+				} else {
+					batchErr.Errors = append(batchErr.Errors, notFound)
+				}
+			}
+		}
+		return batchErr
+	}
+
+	if len(pending) > 0 {
+		return NewNotFoundError(remaining(isPending))
 	}
 
 	return nil
@@ -277,26 +513,43 @@ func (s *Storage) Query(ctx context.Context, pk string, cond SkCondition, opts .
 		TableName: aws.String(s.table),
 	}
 
-	keyCond := expression.Key("PK").Equal(
-		expression.Value(pk),
+	keyCond := expression.Key(s.keys.PK).Equal(
+		expression.Value(rawValue{keyValue(s.keys.PKType, pk)}),
 	)
 	if cond != nil {
-		keyCond = keyCond.And(cond("SK"))
+		if s.keys.NoSortKey {
+			return nil, ErrNoSortKey
+		}
+		keyCond = keyCond.And(cond(s.keys.SK))
 	}
 
-	return s.query(ctx, input, keyCond, opts...)
+	return s.query(ctx, input, s.keys.PK, pk, keyCond, opts...)
+}
+
+func (s *Storage) query(ctx context.Context, input *dynamodb.QueryInput, pkName, pk string, keyCond expression.KeyConditionBuilder, opts ...QueryOption) (QueryInterface, error) {
+	query, err := s.newQuery(ctx, input, pkName, pk, keyCond, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return query, nil
 }
 
-func (s *Storage) query(ctx context.Context, input *dynamodb.QueryInput, keyCond expression.KeyConditionBuilder, opts ...QueryOption) (QueryInterface, error) {
+// newQuery sends the first request of a query and returns its result.
+func (s *Storage) newQuery(ctx context.Context, input *dynamodb.QueryInput, pkName, pk string, keyCond expression.KeyConditionBuilder, opts ...QueryOption) (*Query, error) {
+	input.ReturnConsumedCapacity = s.capacityMode
 	builder := s.newBuilder().WithKeyCondition(keyCond)
 
+	cfg := &readConfig{}
 	for _, apply := range opts {
 		if apply != nil {
-			if b := apply(input, builder); b != nil {
+			if b := apply(input, &configBuilder{builder, cfg}); b != nil {
 				builder = b
 			}
 		}
 	}
+	if filter := s.readFilter(cfg); filter != nil {
+		builder = builder.WithFilter(*filter)
+	}
 	expr, err := builder.Build()
 	if err != nil {
 		return nil, err
@@ -308,39 +561,110 @@ func (s *Storage) query(ctx context.Context, input *dynamodb.QueryInput, keyCond
 	input.ExpressionAttributeNames = expr.Names()
 	input.ExpressionAttributeValues = expr.Values()
 
+	startKey, err := cursorStartKey(input.ExclusiveStartKey, s.table, aws.ToString(input.IndexName), pkName, pk, s.cursorSecret)
+	if err != nil {
+		return nil, err
+	}
+	input.ExclusiveStartKey = startKey
+
 	out, err := s.client.Query(ctx, input)
 	if err != nil {
 		return nil, NewClientError(err)
 	}
 	output := NewOutputFromQueryOutput(out)
+	recordCapacity(ctx, s.capacity, capacities(output.ConsumedCapacity)...)
+
+	query := NewQuery(s.client, input, nil, output, s.decoder)
+	query.cursorSecret = s.cursorSecret
+	query.capacity = s.capacity
+	query.keys = s.keys
+	query.registry = s.registry
+
+	// Count pages have no items to collect, so counting always follows every page
+	if cfg.maxItems > 0 && input.Select != types.SelectCount {
+		if err = query.collect(ctx, cfg.maxItems); err != nil {
+			return nil, err
+		}
+	}
+
+	return query, nil
+}
+
+// readFilter returns the filter of a query or scan: the conditions leaving out expired items, if TTL is
+// enabled, and soft-deleted items, if soft delete is enabled and they are not included, along with the filter of its options if any. Applying it
+// server side keeps counts, projections and page sizes consistent with the items returned.
+// Returns nil if there is nothing to filter.
+func (s *Storage) readFilter(cfg *readConfig) *expression.ConditionBuilder {
+	var filter *expression.ConditionBuilder
+	and := func(cond expression.ConditionBuilder) {
+		if filter != nil {
+			cond = filter.And(cond)
+		}
+		filter = &cond
+	}
+	if s.ttl != "" {
+		and(notExpired(s.ttl, s.clock()))
+	}
+	if s.deletedAt != "" && !cfg.includeDeleted {
+		and(notDeleted(s.deletedAt))
+	}
+	if cfg.filter != nil {
+		if filter == nil {
+			return cfg.filter
+		}
+		combined := cfg.filter.And(*filter)
+		filter = &combined
+	}
+	return filter
+}
+
+func (s *Storage) CountQuery(ctx context.Context, pk string, cond SkCondition, opts ...QueryOption) (CountOutput, error) {
+	query, err := s.Query(ctx, pk, cond, append(slices.Clip(opts), QuerySelectCount())...)
+	if err != nil {
+		return CountOutput{}, err
+	}
 
-	return NewQuery(s.client, input, nil, output, s.decoder), nil
+	return countPages(ctx, query)
 }
 
 func (s *Storage) QueryGSI1(ctx context.Context, pk string, cond SkCondition, opts ...QueryOption) (QueryInterface, error) {
-	return s.queryGSI(ctx, "GSI1", pk, cond, opts...)
+	return s.queryIndex(ctx, s.keys.GSI1, pk, cond, opts...)
 }
 
 func (s *Storage) QueryGSI2(ctx context.Context, pk string, cond SkCondition, opts ...QueryOption) (QueryInterface, error) {
-	return s.queryGSI(ctx, "GSI2", pk, cond, opts...)
+	return s.queryIndex(ctx, s.keys.GSI2, pk, cond, opts...)
+}
+
+func (s *Storage) QueryIndex(ctx context.Context, name, pk string, cond SkCondition, opts ...QueryOption) (QueryInterface, error) {
+	index, ok := s.keys.index(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIndex, name)
+	}
+
+	return s.queryIndex(ctx, index, pk, cond, opts...)
 }
 
-func (s *Storage) queryGSI(ctx context.Context, index, pk string, cond SkCondition, opts ...QueryOption) (QueryInterface, error) {
+func (s *Storage) queryIndex(ctx context.Context, index IndexSchema, pk string, cond SkCondition, opts ...QueryOption) (QueryInterface, error) {
 	input := &dynamodb.QueryInput{
 		TableName: aws.String(s.table),
-		IndexName: aws.String(index),
+		IndexName: aws.String(index.Name),
 	}
 
-	key := fmt.Sprintf("%sPK", index)
-	keyCond := expression.Key(key).Equal(
-		expression.Value(pk),
+	pkName, pkType := index.PK, index.PKType
+	if index.Local {
+		pkName, pkType = s.keys.PK, s.keys.PKType
+	}
+	keyCond := expression.Key(pkName).Equal(
+		expression.Value(rawValue{keyValue(pkType, pk)}),
 	)
 	if cond != nil {
-		key = fmt.Sprintf("%sSK", index)
-		keyCond = keyCond.And(cond(key))
+		if index.SK == "" {
+			return nil, ErrNoSortKey
+		}
+		keyCond = keyCond.And(cond(index.SK))
 	}
 
-	return s.query(ctx, input, keyCond, opts...)
+	return s.query(ctx, input, pkName, pk, keyCond, opts...)
 }
 
 func (s *Storage) Scan(ctx context.Context, opts ...ScanOption) (QueryInterface, error) {
@@ -351,26 +675,62 @@ func (s *Storage) Scan(ctx context.Context, opts ...ScanOption) (QueryInterface,
 	return s.scan(ctx, input, opts...)
 }
 
+func (s *Storage) CountScan(ctx context.Context, opts ...ScanOption) (CountOutput, error) {
+	query, err := s.Scan(ctx, append(slices.Clip(opts), ScanSelectCount())...)
+	if err != nil {
+		return CountOutput{}, err
+	}
+
+	return countPages(ctx, query)
+}
+
+// countPages sums the counts of every page of the query.
+func countPages(ctx context.Context, query QueryInterface) (CountOutput, error) {
+	var out CountOutput
+	for query.NextPage(ctx) {
+		out.Count += int64(query.Count())
+		out.ScannedCount += int64(query.ScannedCount())
+	}
+
+	return out, query.Error()
+}
+
 func (s *Storage) ScanGSI1(ctx context.Context, opts ...ScanOption) (QueryInterface, error) {
-	return s.scanGSI(ctx, "GSI1", opts...)
+	return s.scanIndex(ctx, s.keys.GSI1.Name, opts...)
 }
 
 func (s *Storage) ScanGSI2(ctx context.Context, opts ...ScanOption) (QueryInterface, error) {
-	return s.scanGSI(ctx, "GSI2", opts...)
+	return s.scanIndex(ctx, s.keys.GSI2.Name, opts...)
 }
 
 func (s *Storage) scan(ctx context.Context, input *dynamodb.ScanInput, opts ...ScanOption) (QueryInterface, error) {
+	query, err := s.newScan(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return query, nil
+}
+
+func (s *Storage) newScan(ctx context.Context, input *dynamodb.ScanInput, opts ...ScanOption) (*Query, error) {
+	input.ReturnConsumedCapacity = s.capacityMode
 	builder := s.newBuilder()
-	var nextBuilder BuilderInterface
+	build := false
+	cfg := &readConfig{}
 	for _, apply := range opts {
 		if apply != nil {
-			if b := apply(input, builder); b != nil {
-				nextBuilder = b
+			if b := apply(input, &configBuilder{builder, cfg}); b != nil {
+				builder = b
+				build = true
 			}
 		}
 	}
-	if nextBuilder != nil {
-		expr, err := nextBuilder.Build()
+	if filter := s.readFilter(cfg); filter != nil {
+		builder = builder.WithFilter(*filter)
+		build = true
+	}
+	if build {
+		expr, err := builder.Build()
 		if err != nil {
 			return nil, err
 		}
@@ -380,16 +740,44 @@ func (s *Storage) scan(ctx context.Context, input *dynamodb.ScanInput, opts ...S
 		input.ExpressionAttributeValues = expr.Values()
 	}
 
+	startKey, err := cursorStartKey(input.ExclusiveStartKey, s.table, aws.ToString(input.IndexName), "", "", s.cursorSecret)
+	if err != nil {
+		return nil, err
+	}
+	input.ExclusiveStartKey = startKey
+
 	out, err := s.client.Scan(ctx, input)
 	if err != nil {
 		return nil, NewClientError(err)
 	}
 	output := NewOutputFromScanOutput(out)
+	recordCapacity(ctx, s.capacity, capacities(output.ConsumedCapacity)...)
+
+	query := NewQuery(s.client, nil, input, output, s.decoder)
+	query.cursorSecret = s.cursorSecret
+	query.capacity = s.capacity
+	query.keys = s.keys
+	query.registry = s.registry
+
+	// Count pages have no items to collect, so counting always follows every page
+	if cfg.maxItems > 0 && input.Select != types.SelectCount {
+		if err = query.collect(ctx, cfg.maxItems); err != nil {
+			return nil, err
+		}
+	}
 
-	return NewQuery(s.client, nil, input, output, s.decoder), nil
+	return query, nil
 }
 
-func (s *Storage) scanGSI(ctx context.Context, index string, opts ...ScanOption) (QueryInterface, error) {
+func (s *Storage) ScanIndex(ctx context.Context, name string, opts ...ScanOption) (QueryInterface, error) {
+	if _, ok := s.keys.index(name); !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownIndex, name)
+	}
+
+	return s.scanIndex(ctx, name, opts...)
+}
+
+func (s *Storage) scanIndex(ctx context.Context, index string, opts ...ScanOption) (QueryInterface, error) {
 	input := &dynamodb.ScanInput{
 		TableName: aws.String(s.table),
 		IndexName: aws.String(index),
@@ -399,26 +787,37 @@ func (s *Storage) scanGSI(ctx context.Context, index string, opts ...ScanOption)
 }
 
 func (s *Storage) Update(ctx context.Context, e Entity, update expression.UpdateBuilder, opts ...UpdateOption) error {
-	pk, sk := e.PkSk()
-	if pk == "" {
-		return ErrEntityPkNotSet
+	pk, sk, err := s.keys.entityKey(e)
+	if err != nil {
+		return err
 	}
-	if sk == "" {
-		return ErrEntitySkNotSet
+	if err = beforeUpdate(e); err != nil {
+		return err
 	}
 
 	input := &dynamodb.UpdateItemInput{
-		TableName: aws.String(s.table),
-		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: pk},
-			"SK": &types.AttributeValueMemberS{Value: sk},
-		},
+		TableName:              aws.String(s.table),
+		Key:                    s.keys.key(pk, sk),
+		ReturnConsumedCapacity: s.capacityMode,
+	}
+
+	now := s.clock()
+	update = touchUpdate(e, update, now)
+
+	lock := lockVersion(e, false, s.keys.PK)
+	if lock.conditional() {
+		update = update.Add(expression.Name(VersionAttribute), expression.Value(1))
 	}
 
 	builder := s.newBuilder().WithUpdate(update)
+	if lock.conditional() {
+		input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+		builder = builder.WithCondition(*lock.condition)
+	}
+
 	for _, apply := range opts {
 		if apply != nil {
-			if b := apply(input, builder); b != nil {
+			if b := apply(input, lock.wrap(builder)); b != nil {
 				builder = b
 			}
 		}
@@ -434,40 +833,52 @@ func (s *Storage) Update(ctx context.Context, e Entity, update expression.Update
 
 	out, err := s.client.UpdateItem(ctx, input)
 	if err != nil {
-		return NewClientError(err)
+		return lock.clientError(err)
 	}
+	recordCapacity(ctx, s.capacity, capacities(out.ConsumedCapacity)...)
+	lock.commit()
+	touched(e, now)
 
-	if (input.ReturnValues == ALL_NEW || input.ReturnValues == UPDATED_NEW) && out.Attributes != nil {
-		if err := s.decoder.Decode(out.Attributes, e); err != nil {
+	if input.ReturnValues == ALL_NEW && out.Attributes != nil {
+		return decodeItem(s.decoder, s.keys, out.Attributes, e)
+	}
+	if input.ReturnValues == UPDATED_NEW && out.Attributes != nil {
+		if err := s.decoder.Decode(s.keys.withoutKeys(out.Attributes), Unwrap(e)); err != nil {
 			return fmt.Errorf("%w: %v", ErrEntityDecode, err)
 		}
+		return afterLoad(e)
 	}
 
 	return nil
 }
 
 func (s *Storage) Remove(ctx context.Context, e Entity, opts ...RemoveOption) error {
-	pk, sk := e.PkSk()
-	if pk == "" {
-		return ErrEntityPkNotSet
+	pk, sk, err := s.keys.entityKey(e)
+	if err != nil {
+		return err
 	}
-	if sk == "" {
-		return ErrEntitySkNotSet
+	if err = beforeRemove(e); err != nil {
+		return err
 	}
 
 	input := &dynamodb.DeleteItemInput{
-		TableName: aws.String(s.table),
-		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: pk},
-			"SK": &types.AttributeValueMemberS{Value: sk},
-		},
+		TableName:              aws.String(s.table),
+		Key:                    s.keys.key(pk, sk),
+		ReturnConsumedCapacity: s.capacityMode,
 	}
 
 	builder := s.newBuilder()
 	var nextBuilder BuilderInterface
+
+	lock := lockVersion(e, false, s.keys.PK)
+	if lock.conditional() {
+		input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+		nextBuilder = builder.WithCondition(*lock.condition)
+	}
+
 	for _, apply := range opts {
 		if apply != nil {
-			if b := apply(input, builder); b != nil {
+			if b := apply(input, lock.wrap(builder)); b != nil {
 				nextBuilder = b
 			}
 		}
@@ -482,15 +893,29 @@ func (s *Storage) Remove(ctx context.Context, e Entity, opts ...RemoveOption) er
 		input.ExpressionAttributeValues = expr.Values()
 	}
 
-	_, err := s.client.DeleteItem(ctx, input)
+	output, err := s.client.DeleteItem(ctx, input)
 	if err != nil {
-		return NewClientError(err)
+		return lock.clientError(err)
 	}
-	return nil
+	recordCapacity(ctx, s.capacity, capacities(output.ConsumedCapacity)...)
+
+	return afterRemove(e)
 }
 
 func (s *Storage) Transaction() TransactionInterface {
-	return NewTransaction(s.table, s.client, s.encoder, s.newBuilder)
+	tx := NewTransaction(s.table, s.client, s.encoder, s.newBuilder)
+	tx.capacityMode = s.capacityMode
+	tx.capacity = s.capacity
+	tx.clock = s.clock
+	tx.keys = s.keys
+	tx.registry = s.registry
+	tx.ttl = s.ttl
+
+	return tx
+}
+
+func (s *Storage) ConsumedCapacity() *ConsumedCapacity {
+	return s.capacity
 }
 
 func (s *Storage) BatchRemove(ctx context.Context, entities ...Entity) error {
@@ -501,56 +926,176 @@ func (s *Storage) BatchRemove(ctx context.Context, entities ...Entity) error {
 	batches := make([]types.WriteRequest, 0, len(entities))
 
 	for _, e := range entities {
-		pk, sk := e.PkSk()
-		if pk == "" {
-			return ErrEntityPkNotSet
+		pk, sk, err := s.keys.entityKey(e)
+		if err != nil {
+			return err
 		}
-		if sk == "" {
-			return ErrEntitySkNotSet
+		if err = beforeRemove(e); err != nil {
+			return err
 		}
 
 		batches = append(batches, types.WriteRequest{
 			DeleteRequest: &types.DeleteRequest{
-				Key: map[string]types.AttributeValue{
-					"PK": &types.AttributeValueMemberS{Value: pk},
-					"SK": &types.AttributeValueMemberS{Value: sk},
-				},
+				Key: s.keys.key(pk, sk),
 			},
 		})
 	}
 
-	return s.batchWrite(ctx, batches)
+	return s.batchWrite(ctx, batches, entities, afterRemove)
 }
 
-func (s *Storage) batchWrite(ctx context.Context, requests []types.WriteRequest) error {
+// batchWrite sends the write requests in chunks, using up to batchWorkers concurrent
+// requests and re-submitting unprocessed items with backoff. The entities slice must be
+// aligned with the requests slice so that failed requests can be reported as entities
+// in a BatchError. Chunks that are not sent yet when ctx is done are reported as failed.
+// The after hook is called on the entities that were written.
+func (s *Storage) batchWrite(ctx context.Context, requests []types.WriteRequest, entities []Entity, after func(Entity) error) error {
 	if len(requests) == 0 {
 		return nil
 	}
 
 	const batchSize = 25
 
-	for i := 0; i < len(requests); i += batchSize {
-		end := i + batchSize
-		if end > len(requests) {
-			end = len(requests)
+	// Each worker only touches the indexes of its own chunk, so no locking is needed.
+	failed := make([]bool, len(requests))
+	causes := make([]error, len(requests))
+	fail := func(start, end int, unprocessed []types.WriteRequest, err error) {
+		keys := make(map[[2]string]bool, len(unprocessed))
+		for _, r := range unprocessed {
+			keys[s.requestKeys(r)] = true
+		}
+		for i := start; i < end; i++ {
+			if keys[s.requestKeys(requests[i])] {
+				failed[i] = true
+				causes[i] = err
+			}
+		}
+	}
+
+	workers := s.batchWorkers
+	if chunks := (len(requests) + batchSize - 1) / batchSize; workers > chunks {
+		workers = chunks
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	chunks := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range chunks {
+				end := start + batchSize
+				if end > len(requests) {
+					end = len(requests)
+				}
+
+				unprocessed, err := s.writeBatch(ctx, requests[start:end])
+				fail(start, end, unprocessed, err)
+			}
+		}()
+	}
+
+feed:
+	for start := 0; start < len(requests); start += batchSize {
+		select {
+		case chunks <- start:
+		case <-ctx.Done():
+			fail(start, len(requests), requests[start:], ctx.Err())
+			break feed
+		}
+	}
+	close(chunks)
+	wg.Wait()
+
+	var errs []error
+	batchErr := &BatchError{}
+	for i, e := range entities {
+		if failed[i] {
+			batchErr.Entities = append(batchErr.Entities, e)
+			batchErr.Errors = append(batchErr.Errors, causes[i])
+		} else if err := after(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(batchErr.Entities) > 0 {
+		if len(errs) == 0 {
+			return batchErr
+		}
+		errs = append([]error{batchErr}, errs...)
+	}
+
+	return errors.Join(errs...)
+}
+
+// writeBatch sends a single BatchWriteItem chunk and retries its unprocessed items.
+// It returns the requests that were still unprocessed when retries ran out or an error occurred.
+func (s *Storage) writeBatch(ctx context.Context, batch []types.WriteRequest) ([]types.WriteRequest, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err := s.backoff(ctx, attempt); err != nil {
+				return batch, err
+			}
 		}
 
-		batch := requests[i:end]
 		input := &dynamodb.BatchWriteItemInput{
 			RequestItems: map[string][]types.WriteRequest{
 				s.table: batch,
 			},
+			ReturnConsumedCapacity: s.capacityMode,
 		}
 
 		output, err := s.client.BatchWriteItem(ctx, input)
 		if err != nil {
-			return NewClientError(err)
+			return batch, NewClientError(err)
 		}
+		recordCapacity(ctx, s.capacity, output.ConsumedCapacity...)
 
-		if unprocessed, ok := output.UnprocessedItems[s.table]; ok && len(unprocessed) > 0 {
-			return ErrBatch
+		batch = output.UnprocessedItems[s.table]
+		if len(batch) == 0 || attempt >= s.batchRetries {
+			return batch, nil
 		}
 	}
+}
 
-	return nil
+// requestKeys returns the PK/SK of the item targeted by a put or delete request.
+func (s *Storage) requestKeys(r types.WriteRequest) [2]string {
+	if r.PutRequest != nil {
+		return s.keys.keys(r.PutRequest.Item)
+	}
+	if r.DeleteRequest != nil {
+		return s.keys.keys(r.DeleteRequest.Key)
+	}
+	return [2]string{}
+}
+
+// maxBatchRetryDelay caps the exponential backoff between batch retries,
+// unless the base delay is greater.
+const maxBatchRetryDelay = 10 * time.Second
+
+// backoff waits before the given retry attempt (starting at 1) using exponential
+// backoff with full jitter. It returns early with the context error if ctx is done.
+func (s *Storage) backoff(ctx context.Context, attempt int) error {
+	// Doubling stops at the cap so that the delay never overflows on large retry counts.
+	limit := max(s.batchDelay, maxBatchRetryDelay)
+	delay := s.batchDelay
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	delay = min(delay, limit)
+	if delay > 0 {
+		delay = time.Duration(rand.Int64N(int64(delay))) + 1
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
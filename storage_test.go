@@ -2,7 +2,12 @@ package dynamorm_test
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
@@ -197,7 +202,10 @@ func TestStorageBatchSave(t *testing.T) {
 
 	dynamo := NewMockDynamoDB(ctrl)
 	enc := NewMockEncoderInterface(ctrl)
-	storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithEncoder(enc))
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithEncoder(enc),
+		dynamorm.WithBatchRetry(1, 0),
+	)
 
 	t.Run("should batch save entities", func(t *testing.T) {
 		e1 := NewMockEntity(ctrl)
@@ -256,7 +264,55 @@ func TestStorageBatchSave(t *testing.T) {
 		require.NoError(t, err)
 	})
 
-	t.Run("should return error with unprocessed items", func(t *testing.T) {
+	t.Run("should retry unprocessed items", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().BeforeSave().Return(nil)
+		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
+		e1.EXPECT().GSI1().Return("", "")
+		e1.EXPECT().GSI2().Return("", "")
+
+		e2 := NewMockEntity(ctrl)
+		e2.EXPECT().BeforeSave().Return(nil)
+		e2.EXPECT().PkSk().Return("PK#2", "SK#2")
+		e2.EXPECT().GSI1().Return("", "")
+		e2.EXPECT().GSI2().Return("", "")
+
+		enc.EXPECT().Encode(e1).Return(map[string]types.AttributeValue{}, nil)
+		enc.EXPECT().Encode(e2).Return(map[string]types.AttributeValue{}, nil)
+
+		unprocessed := []types.WriteRequest{
+			{
+				PutRequest: &types.PutRequest{
+					Item: map[string]types.AttributeValue{
+						"PK": &types.AttributeValueMemberS{Value: "PK#2"},
+						"SK": &types.AttributeValueMemberS{Value: "SK#2"},
+					},
+				},
+			},
+		}
+
+		gomock.InOrder(
+			dynamo.EXPECT().
+				BatchWriteItem(gomock.Any(), gomock.Any()).
+				Return(&dynamodb.BatchWriteItemOutput{
+					UnprocessedItems: map[string][]types.WriteRequest{
+						"TestTable": unprocessed,
+					},
+				}, nil),
+			dynamo.EXPECT().
+				BatchWriteItem(gomock.Any(), &dynamodb.BatchWriteItemInput{
+					RequestItems: map[string][]types.WriteRequest{
+						"TestTable": unprocessed,
+					},
+				}).
+				Return(&dynamodb.BatchWriteItemOutput{}, nil),
+		)
+
+		err := storage.BatchSave(context.TODO(), e1, e2)
+		require.NoError(t, err)
+	})
+
+	t.Run("should return batch error with unprocessed items", func(t *testing.T) {
 		e1 := NewMockEntity(ctrl)
 		e1.EXPECT().BeforeSave().Return(nil)
 		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
@@ -277,13 +333,69 @@ func TestStorageBatchSave(t *testing.T) {
 			Return(&dynamodb.BatchWriteItemOutput{
 				UnprocessedItems: map[string][]types.WriteRequest{
 					"TestTable": {
-						{},
+						{
+							PutRequest: &types.PutRequest{
+								Item: map[string]types.AttributeValue{
+									"PK": &types.AttributeValueMemberS{Value: "PK#2"},
+									"SK": &types.AttributeValueMemberS{Value: "SK#2"},
+								},
+							},
+						},
 					},
 				},
-			}, nil)
+			}, nil).
+			Times(2)
 
 		err := storage.BatchSave(context.TODO(), e1, e2)
 		require.ErrorIs(t, err, dynamorm.ErrBatch)
+
+		var batchErr *dynamorm.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, []dynamorm.Entity{e2}, batchErr.Entities)
+	})
+
+	t.Run("should send remaining chunks after unprocessed items", func(t *testing.T) {
+		entities := make([]dynamorm.Entity, 26)
+		for i := range entities {
+			e := NewMockEntity(ctrl)
+			e.EXPECT().BeforeSave().Return(nil)
+			e.EXPECT().PkSk().Return(fmt.Sprintf("PK#%d", i), "SK")
+			e.EXPECT().GSI1().Return("", "")
+			e.EXPECT().GSI2().Return("", "")
+			enc.EXPECT().Encode(e).Return(map[string]types.AttributeValue{}, nil)
+			entities[i] = e
+		}
+
+		dynamo.EXPECT().
+			BatchWriteItem(gomock.Any(), gomock.Cond(func(input *dynamodb.BatchWriteItemInput) bool {
+				return len(input.RequestItems["TestTable"]) == 25
+			})).
+			Return(&dynamodb.BatchWriteItemOutput{}, nil)
+		dynamo.EXPECT().
+			BatchWriteItem(gomock.Any(), gomock.Cond(func(input *dynamodb.BatchWriteItemInput) bool {
+				return len(input.RequestItems["TestTable"]) == 1
+			})).
+			Return(&dynamodb.BatchWriteItemOutput{
+				UnprocessedItems: map[string][]types.WriteRequest{
+					"TestTable": {
+						{
+							PutRequest: &types.PutRequest{
+								Item: map[string]types.AttributeValue{
+									"PK": &types.AttributeValueMemberS{Value: "PK#25"},
+									"SK": &types.AttributeValueMemberS{Value: "SK"},
+								},
+							},
+						},
+					},
+				},
+			}, nil).
+			Times(2)
+
+		err := storage.BatchSave(context.TODO(), entities...)
+
+		var batchErr *dynamorm.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, []dynamorm.Entity{entities[25]}, batchErr.Entities)
 	})
 
 	t.Run("should return error if empty pk", func(t *testing.T) {
@@ -352,6 +464,11 @@ func TestStorageBatchSave(t *testing.T) {
 
 		err := storage.BatchSave(context.TODO(), e1, e2)
 		require.ErrorIs(t, err, dynamorm.ErrClient)
+		require.ErrorIs(t, err, dynamorm.ErrBatch)
+
+		var batchErr *dynamorm.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, []dynamorm.Entity{e1, e2}, batchErr.Entities)
 	})
 }
 
@@ -360,7 +477,7 @@ func TestStorageBatchRemove(t *testing.T) {
 	t.Cleanup(ctrl.Finish)
 
 	dynamo := NewMockDynamoDB(ctrl)
-	storage := dynamorm.NewStorage("TestTable", dynamo)
+	storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithBatchRetry(1, 0))
 
 	t.Run("should remove none", func(t *testing.T) {
 		err := storage.BatchRemove(context.TODO())
@@ -416,12 +533,49 @@ func TestStorageBatchRemove(t *testing.T) {
 			BatchWriteItem(gomock.Any(), gomock.Any()).
 			Return(&dynamodb.BatchWriteItemOutput{
 				UnprocessedItems: map[string][]types.WriteRequest{
-					"TestTable": {{}},
+					"TestTable": {
+						{
+							DeleteRequest: &types.DeleteRequest{
+								Key: map[string]types.AttributeValue{
+									"PK": &types.AttributeValueMemberS{Value: "PK#1"},
+									"SK": &types.AttributeValueMemberS{Value: "SK#1"},
+								},
+							},
+						},
+					},
 				},
-			}, nil)
+			}, nil).
+			Times(2)
 
 		err := storage.BatchRemove(context.TODO(), e1, e2)
 		require.ErrorIs(t, err, dynamorm.ErrBatch)
+
+		var batchErr *dynamorm.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, []dynamorm.Entity{e1}, batchErr.Entities)
+	})
+
+	t.Run("should stop retrying when context is done", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithBatchRetry(1, time.Hour))
+
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
+
+		ctx, cancel := context.WithCancel(context.TODO())
+
+		dynamo.EXPECT().
+			BatchWriteItem(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+				cancel()
+				return &dynamodb.BatchWriteItemOutput{UnprocessedItems: input.RequestItems}, nil
+			})
+
+		err := storage.BatchRemove(ctx, e1)
+		require.ErrorIs(t, err, context.Canceled)
+
+		var batchErr *dynamorm.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, []dynamorm.Entity{e1}, batchErr.Entities)
 	})
 
 	t.Run("should return error if empty pk", func(t *testing.T) {
@@ -457,6 +611,110 @@ func TestStorageBatchRemove(t *testing.T) {
 	})
 }
 
+func TestStorageBatchConcurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithBatchRetry(0, 0),
+		dynamorm.WithBatchConcurrency(3),
+	)
+
+	newEntities := func(n int) []dynamorm.Entity {
+		entities := make([]dynamorm.Entity, n)
+		for i := range entities {
+			e := NewMockEntity(ctrl)
+			e.EXPECT().PkSk().Return(fmt.Sprintf("PK#%d", i), "SK")
+			entities[i] = e
+		}
+		return entities
+	}
+
+	t.Run("should send chunks in parallel", func(t *testing.T) {
+		entities := newEntities(75)
+
+		arrived := make(chan struct{}, 3)
+		ready := make(chan struct{})
+		go func() {
+			for i := 0; i < 3; i++ {
+				<-arrived
+			}
+			close(ready)
+		}()
+
+		dynamo.EXPECT().
+			BatchWriteItem(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(context.Context, *dynamodb.BatchWriteItemInput, ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+				arrived <- struct{}{}
+				select {
+				case <-ready:
+					return &dynamodb.BatchWriteItemOutput{}, nil
+				case <-time.After(time.Second):
+					return nil, assert.AnError
+				}
+			}).
+			Times(3)
+
+		err := storage.BatchRemove(context.TODO(), entities...)
+		require.NoError(t, err)
+	})
+
+	t.Run("should aggregate errors per entity", func(t *testing.T) {
+		entities := newEntities(50)
+
+		dynamo.EXPECT().
+			BatchWriteItem(gomock.Any(), gomock.Cond(func(input *dynamodb.BatchWriteItemInput) bool {
+				return itemPK(input.RequestItems["TestTable"][0].DeleteRequest.Key) == "PK#0"
+			})).
+			Return(nil, assert.AnError)
+		dynamo.EXPECT().
+			BatchWriteItem(gomock.Any(), gomock.Cond(func(input *dynamodb.BatchWriteItemInput) bool {
+				return itemPK(input.RequestItems["TestTable"][0].DeleteRequest.Key) == "PK#25"
+			})).
+			Return(&dynamodb.BatchWriteItemOutput{}, nil)
+
+		err := storage.BatchRemove(context.TODO(), entities...)
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+
+		var batchErr *dynamorm.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, entities[:25], batchErr.Entities)
+		require.Len(t, batchErr.Errors, 25)
+		for _, entityErr := range batchErr.Errors {
+			require.ErrorIs(t, entityErr, assert.AnError)
+		}
+	})
+
+	t.Run("should report unsent chunks when context is done", func(t *testing.T) {
+		entities := newEntities(60)
+
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+
+		dynamo.EXPECT().
+			BatchWriteItem(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, _ *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+				return nil, ctx.Err()
+			}).
+			AnyTimes()
+
+		err := storage.BatchRemove(ctx, entities...)
+		require.ErrorIs(t, err, context.Canceled)
+
+		var batchErr *dynamorm.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, entities, batchErr.Entities)
+	})
+}
+
+func itemPK(key map[string]types.AttributeValue) string {
+	if v, ok := key["PK"].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
+
 func TestStorageGet(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	t.Cleanup(ctrl.Finish)
@@ -602,140 +860,3012 @@ func TestStorageGet(t *testing.T) {
 	})
 }
 
-func TestStorageQuery(t *testing.T) {
+func TestStorageBatchGet(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	t.Cleanup(ctrl.Finish)
 
 	dynamo := NewMockDynamoDB(ctrl)
-	storage := dynamorm.NewStorage("TestTable", dynamo)
+	dec := NewMockDecoderInterface(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithDecoder(dec),
+		dynamorm.WithBatchRetry(1, 0),
+	)
 
-	out := &dynamodb.QueryOutput{
-		Count: 1,
+	item1 := map[string]types.AttributeValue{
+		"PK":   &types.AttributeValueMemberS{Value: "PK#1"},
+		"SK":   &types.AttributeValueMemberS{Value: "SK#1"},
+		"Attr": &types.AttributeValueMemberS{Value: "value1"},
+	}
+	item2 := map[string]types.AttributeValue{
+		"PK":   &types.AttributeValueMemberS{Value: "PK#2"},
+		"SK":   &types.AttributeValueMemberS{Value: "SK#2"},
+		"Attr": &types.AttributeValueMemberS{Value: "value2"},
+	}
+	// Key attributes are not decoded into entities.
+	attrs1 := map[string]types.AttributeValue{"Attr": item1["Attr"]}
+	attrs2 := map[string]types.AttributeValue{"Attr": item2["Attr"]}
+	key1 := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "PK#1"},
+		"SK": &types.AttributeValueMemberS{Value: "SK#1"},
+	}
+	key2 := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "PK#2"},
+		"SK": &types.AttributeValueMemberS{Value: "SK#2"},
 	}
 
-	t.Run("should return query", func(t *testing.T) {
-		dynamo.EXPECT().
-			Query(context.TODO(), &dynamodb.QueryInput{
-				TableName:              aws.String("TestTable"),
-				KeyConditionExpression: aws.String("#0 = :0"),
-				ExpressionAttributeValues: map[string]types.AttributeValue{
-					":0": &types.AttributeValueMemberS{Value: "pk-value"},
-				},
-				ExpressionAttributeNames: map[string]string{
-					"#0": "PK",
-				},
-			}).
-			Return(out, nil)
-
-		query, err := storage.Query(context.TODO(), "pk-value", nil)
+	t.Run("should get none", func(t *testing.T) {
+		err := storage.BatchGet(context.TODO())
 		require.NoError(t, err)
-		require.NotNil(t, query)
-		require.Equal(t, int32(1), query.Count())
 	})
 
-	t.Run("should return query with options", func(t *testing.T) {
+	t.Run("should batch get entities", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
+
+		e2 := NewMockEntity(ctrl)
+		e2.EXPECT().PkSk().Return("PK#2", "SK#2")
+
 		dynamo.EXPECT().
-			Query(context.TODO(), &dynamodb.QueryInput{
-				TableName:              aws.String("TestTable"),
-				KeyConditionExpression: aws.String("(#0 = :0) AND (#1 = :1)"),
-				ExpressionAttributeValues: map[string]types.AttributeValue{
-					":0": &types.AttributeValueMemberS{Value: "pk-value"},
-					":1": &types.AttributeValueMemberS{Value: "sk-value"},
-				},
-				ExpressionAttributeNames: map[string]string{
-					"#0": "PK",
-					"#1": "SK",
+			BatchGetItem(context.TODO(), &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{
+					"TestTable": {Keys: []map[string]types.AttributeValue{key1, key2}},
 				},
-				Limit: aws.Int32(1),
 			}).
-			Return(out, nil)
+			Return(&dynamodb.BatchGetItemOutput{
+				Responses: map[string][]map[string]types.AttributeValue{
+					"TestTable": {item2, item1},
+				},
+			}, nil)
 
-		query, err := storage.Query(context.TODO(), "pk-value",
-			dynamorm.SkEQ("sk-value"),
-			dynamorm.QueryLimit(1),
-		)
+		dec.EXPECT().Decode(attrs1, e1).Return(nil)
+		dec.EXPECT().Decode(attrs2, e2).Return(nil)
+
+		err := storage.BatchGet(context.TODO(), e1, e2)
 		require.NoError(t, err)
-		require.NotNil(t, query)
-		require.Equal(t, int32(1), query.Count())
 	})
 
-	t.Run("should query by GSI1", func(t *testing.T) {
+	t.Run("should request duplicated keys once", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
+
+		e2 := NewMockEntity(ctrl)
+		e2.EXPECT().PkSk().Return("PK#1", "SK#1")
+
 		dynamo.EXPECT().
-			Query(context.TODO(), &dynamodb.QueryInput{
-				TableName:              aws.String("TestTable"),
-				IndexName:              aws.String("GSI1"),
-				KeyConditionExpression: aws.String("#0 = :0"),
-				ExpressionAttributeValues: map[string]types.AttributeValue{
-					":0": &types.AttributeValueMemberS{Value: "gsi1-pk-value"},
-				},
-				ExpressionAttributeNames: map[string]string{
-					"#0": "GSI1PK",
+			BatchGetItem(context.TODO(), &dynamodb.BatchGetItemInput{
+				RequestItems: map[string]types.KeysAndAttributes{
+					"TestTable": {Keys: []map[string]types.AttributeValue{key1}},
 				},
 			}).
-			Return(out, nil)
+			Return(&dynamodb.BatchGetItemOutput{
+				Responses: map[string][]map[string]types.AttributeValue{
+					"TestTable": {item1},
+				},
+			}, nil)
 
-		query, err := storage.QueryGSI1(context.TODO(), "gsi1-pk-value", nil)
+		dec.EXPECT().Decode(attrs1, e1).Return(nil)
+		dec.EXPECT().Decode(attrs1, e2).Return(nil)
+
+		err := storage.BatchGet(context.TODO(), e1, e2)
 		require.NoError(t, err)
-		require.NotNil(t, query)
-		require.Equal(t, int32(1), query.Count())
 	})
 
-	t.Run("should query by GSI1 with options", func(t *testing.T) {
-		dynamo.EXPECT().
-			Query(context.TODO(), &dynamodb.QueryInput{
-				TableName:              aws.String("TestTable"),
-				IndexName:              aws.String("GSI1"),
-				KeyConditionExpression: aws.String("(#0 = :0) AND (#1 = :1)"),
-				ExpressionAttributeValues: map[string]types.AttributeValue{
-					":0": &types.AttributeValueMemberS{Value: "gsi1-pk-value"},
-					":1": &types.AttributeValueMemberS{Value: "gsi1-sk-value"},
-				},
-				ExpressionAttributeNames: map[string]string{
-					"#0": "GSI1PK",
-					"#1": "GSI1SK",
-				},
-				Limit: aws.Int32(1),
-			}).
-			Return(out, nil)
+	t.Run("should retry unprocessed keys", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
 
-		query, err := storage.QueryGSI1(context.TODO(), "gsi1-pk-value",
-			dynamorm.SkEQ("gsi1-sk-value"),
-			dynamorm.QueryLimit(1),
+		e2 := NewMockEntity(ctrl)
+		e2.EXPECT().PkSk().Return("PK#2", "SK#2")
+
+		gomock.InOrder(
+			dynamo.EXPECT().
+				BatchGetItem(context.TODO(), gomock.Any()).
+				Return(&dynamodb.BatchGetItemOutput{
+					Responses: map[string][]map[string]types.AttributeValue{
+						"TestTable": {item1},
+					},
+					UnprocessedKeys: map[string]types.KeysAndAttributes{
+						"TestTable": {Keys: []map[string]types.AttributeValue{key2}},
+					},
+				}, nil),
+			dynamo.EXPECT().
+				BatchGetItem(context.TODO(), &dynamodb.BatchGetItemInput{
+					RequestItems: map[string]types.KeysAndAttributes{
+						"TestTable": {Keys: []map[string]types.AttributeValue{key2}},
+					},
+				}).
+				Return(&dynamodb.BatchGetItemOutput{
+					Responses: map[string][]map[string]types.AttributeValue{
+						"TestTable": {item2},
+					},
+				}, nil),
 		)
-		require.NoError(t, err)
-		require.NotNil(t, query)
-		require.Equal(t, int32(1), query.Count())
-	})
 
-	t.Run("should query by GSI2", func(t *testing.T) {
-		dynamo.EXPECT().
-			Query(context.TODO(), &dynamodb.QueryInput{
-				TableName:              aws.String("TestTable"),
-				IndexName:              aws.String("GSI2"),
-				KeyConditionExpression: aws.String("#0 = :0"),
-				ExpressionAttributeValues: map[string]types.AttributeValue{
-					":0": &types.AttributeValueMemberS{Value: "gsi2-pk-value"},
-				},
-				ExpressionAttributeNames: map[string]string{
-					"#0": "GSI2PK",
-				},
-			}).
-			Return(out, nil)
+		dec.EXPECT().Decode(attrs1, e1).Return(nil)
+		dec.EXPECT().Decode(attrs2, e2).Return(nil)
 
-		query, err := storage.QueryGSI2(context.TODO(), "gsi2-pk-value", nil)
+		err := storage.BatchGet(context.TODO(), e1, e2)
 		require.NoError(t, err)
-		require.NotNil(t, query)
-		require.Equal(t, int32(1), query.Count())
 	})
 
-	t.Run("should return client error", func(t *testing.T) {
-		dynamo.EXPECT().
-			Query(gomock.Any(), gomock.Any()).
-			Return(nil, assert.AnError)
+	t.Run("should return batch error with unprocessed keys", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
 
-		query, err := storage.Query(context.TODO(), "PK#1", nil)
+		e2 := NewMockEntity(ctrl)
+		e2.EXPECT().PkSk().Return("PK#2", "SK#2")
+
+		dynamo.EXPECT().
+			BatchGetItem(context.TODO(), gomock.Any()).
+			Return(&dynamodb.BatchGetItemOutput{
+				UnprocessedKeys: map[string]types.KeysAndAttributes{
+					"TestTable": {Keys: []map[string]types.AttributeValue{key2}},
+				},
+			}, nil).
+			Times(2)
+
+		err := storage.BatchGet(context.TODO(), e1, e2)
+		require.ErrorIs(t, err, dynamorm.ErrBatch)
+		require.ErrorIs(t, err, dynamorm.ErrEntityNotFound)
+
+		var notFoundErr *dynamorm.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		require.Equal(t, []dynamorm.Entity{e1}, notFoundErr.Entities)
+
+		var batchErr *dynamorm.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, []dynamorm.Entity{e1, e2}, batchErr.Entities)
+		require.Equal(t, []error{notFoundErr, nil}, batchErr.Errors)
+	})
+
+	t.Run("should chunk keys by 100", func(t *testing.T) {
+		entities := make([]dynamorm.Entity, 101)
+		for i := range entities {
+			e := NewMockEntity(ctrl)
+			e.EXPECT().PkSk().Return(fmt.Sprintf("PK#%d", i), "SK")
+			entities[i] = e
+		}
+
+		dynamo.EXPECT().
+			BatchGetItem(context.TODO(), gomock.Cond(func(input *dynamodb.BatchGetItemInput) bool {
+				return len(input.RequestItems["TestTable"].Keys) == 100
+			})).
+			Return(&dynamodb.BatchGetItemOutput{}, nil)
+		dynamo.EXPECT().
+			BatchGetItem(context.TODO(), gomock.Cond(func(input *dynamodb.BatchGetItemInput) bool {
+				return len(input.RequestItems["TestTable"].Keys) == 1
+			})).
+			Return(&dynamodb.BatchGetItemOutput{}, nil)
+
+		err := storage.BatchGet(context.TODO(), entities...)
+
+		var notFoundErr *dynamorm.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		require.Len(t, notFoundErr.Entities, 101)
+	})
+
+	t.Run("should return not found error", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
+
+		e2 := NewMockEntity(ctrl)
+		e2.EXPECT().PkSk().Return("PK#2", "SK#2")
+
+		dynamo.EXPECT().
+			BatchGetItem(gomock.Any(), gomock.Any()).
+			Return(&dynamodb.BatchGetItemOutput{
+				Responses: map[string][]map[string]types.AttributeValue{
+					"TestTable": {item1},
+				},
+			}, nil)
+
+		dec.EXPECT().Decode(attrs1, e1).Return(nil)
+
+		err := storage.BatchGet(context.TODO(), e1, e2)
+		require.ErrorIs(t, err, dynamorm.ErrEntityNotFound)
+
+		var notFoundErr *dynamorm.NotFoundError
+		require.ErrorAs(t, err, &notFoundErr)
+		require.Equal(t, []dynamorm.Entity{e2}, notFoundErr.Entities)
+	})
+
+	t.Run("should return error if empty pk", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("", "SK#1")
+
+		e2 := NewMockEntity(ctrl)
+		err := storage.BatchGet(context.TODO(), e1, e2)
+		require.ErrorIs(t, err, dynamorm.ErrEntityPkNotSet)
+	})
+
+	t.Run("should return error if empty sk", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("PK#1", "")
+
+		e2 := NewMockEntity(ctrl)
+		err := storage.BatchGet(context.TODO(), e1, e2)
+		require.ErrorIs(t, err, dynamorm.ErrEntitySkNotSet)
+	})
+
+	t.Run("should return client error", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
+
+		dynamo.EXPECT().
+			BatchGetItem(gomock.Any(), gomock.Any()).
+			Return(nil, assert.AnError)
+
+		err := storage.BatchGet(context.TODO(), e1)
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+	})
+
+	t.Run("should return decode error", func(t *testing.T) {
+		e1 := NewMockEntity(ctrl)
+		e1.EXPECT().PkSk().Return("PK#1", "SK#1")
+
+		dynamo.EXPECT().
+			BatchGetItem(gomock.Any(), gomock.Any()).
+			Return(&dynamodb.BatchGetItemOutput{
+				Responses: map[string][]map[string]types.AttributeValue{
+					"TestTable": {item1},
+				},
+			}, nil)
+
+		dec.EXPECT().Decode(attrs1, e1).Return(assert.AnError)
+
+		err := storage.BatchGet(context.TODO(), e1)
+		require.ErrorIs(t, err, dynamorm.ErrEntityDecode)
+	})
+}
+
+func TestStorageQuery(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo)
+
+	out := &dynamodb.QueryOutput{
+		Count: 1,
+	}
+
+	t.Run("should return query", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(context.TODO(), &dynamodb.QueryInput{
+				TableName:              aws.String("TestTable"),
+				KeyConditionExpression: aws.String("#0 = :0"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberS{Value: "pk-value"},
+				},
+				ExpressionAttributeNames: map[string]string{
+					"#0": "PK",
+				},
+			}).
+			Return(out, nil)
+
+		query, err := storage.Query(context.TODO(), "pk-value", nil)
+		require.NoError(t, err)
+		require.NotNil(t, query)
+		require.Equal(t, int32(1), query.Count())
+	})
+
+	t.Run("should return query with options", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(context.TODO(), &dynamodb.QueryInput{
+				TableName:              aws.String("TestTable"),
+				KeyConditionExpression: aws.String("(#0 = :0) AND (#1 = :1)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberS{Value: "pk-value"},
+					":1": &types.AttributeValueMemberS{Value: "sk-value"},
+				},
+				ExpressionAttributeNames: map[string]string{
+					"#0": "PK",
+					"#1": "SK",
+				},
+				Limit: aws.Int32(1),
+			}).
+			Return(out, nil)
+
+		query, err := storage.Query(context.TODO(), "pk-value",
+			dynamorm.SkEQ("sk-value"),
+			dynamorm.QueryLimit(1),
+		)
+		require.NoError(t, err)
+		require.NotNil(t, query)
+		require.Equal(t, int32(1), query.Count())
+	})
+
+	t.Run("should query by GSI1", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(context.TODO(), &dynamodb.QueryInput{
+				TableName:              aws.String("TestTable"),
+				IndexName:              aws.String("GSI1"),
+				KeyConditionExpression: aws.String("#0 = :0"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberS{Value: "gsi1-pk-value"},
+				},
+				ExpressionAttributeNames: map[string]string{
+					"#0": "GSI1PK",
+				},
+			}).
+			Return(out, nil)
+
+		query, err := storage.QueryGSI1(context.TODO(), "gsi1-pk-value", nil)
+		require.NoError(t, err)
+		require.NotNil(t, query)
+		require.Equal(t, int32(1), query.Count())
+	})
+
+	t.Run("should query by GSI1 with options", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(context.TODO(), &dynamodb.QueryInput{
+				TableName:              aws.String("TestTable"),
+				IndexName:              aws.String("GSI1"),
+				KeyConditionExpression: aws.String("(#0 = :0) AND (#1 = :1)"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberS{Value: "gsi1-pk-value"},
+					":1": &types.AttributeValueMemberS{Value: "gsi1-sk-value"},
+				},
+				ExpressionAttributeNames: map[string]string{
+					"#0": "GSI1PK",
+					"#1": "GSI1SK",
+				},
+				Limit: aws.Int32(1),
+			}).
+			Return(out, nil)
+
+		query, err := storage.QueryGSI1(context.TODO(), "gsi1-pk-value",
+			dynamorm.SkEQ("gsi1-sk-value"),
+			dynamorm.QueryLimit(1),
+		)
+		require.NoError(t, err)
+		require.NotNil(t, query)
+		require.Equal(t, int32(1), query.Count())
+	})
+
+	t.Run("should query by GSI2", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(context.TODO(), &dynamodb.QueryInput{
+				TableName:              aws.String("TestTable"),
+				IndexName:              aws.String("GSI2"),
+				KeyConditionExpression: aws.String("#0 = :0"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberS{Value: "gsi2-pk-value"},
+				},
+				ExpressionAttributeNames: map[string]string{
+					"#0": "GSI2PK",
+				},
+			}).
+			Return(out, nil)
+
+		query, err := storage.QueryGSI2(context.TODO(), "gsi2-pk-value", nil)
+		require.NoError(t, err)
+		require.NotNil(t, query)
+		require.Equal(t, int32(1), query.Count())
+	})
+
+	t.Run("should return client error", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			Return(nil, assert.AnError)
+
+		query, err := storage.Query(context.TODO(), "PK#1", nil)
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+		require.Nil(t, query)
+	})
+}
+
+func TestStorageCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	ctx := context.TODO()
+
+	lastKey := map[string]types.AttributeValue{
+		"PK":     &types.AttributeValueMemberS{Value: "PK#1"},
+		"SK":     &types.AttributeValueMemberN{Value: "42"},
+		"GSI1PK": &types.AttributeValueMemberS{Value: "GSI1PK#1"},
+	}
+
+	cursorFor := func(t *testing.T, query func() (dynamorm.QueryInterface, error)) string {
+		t.Helper()
+		dynamo.EXPECT().Query(ctx, gomock.Any()).Return(&dynamodb.QueryOutput{LastEvaluatedKey: lastKey}, nil)
+
+		q, err := query()
+		require.NoError(t, err)
+		cursor, err := q.Cursor()
+		require.NoError(t, err)
+		require.NotEmpty(t, cursor)
+		return cursor
+	}
+
+	expectStartKey := func() {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, lastKey, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{}, nil
+			})
+	}
+
+	t.Run("should resume query from cursor", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo)
+		cursor := cursorFor(t, func() (dynamorm.QueryInterface, error) {
+			return storage.Query(ctx, "PK#1", nil)
+		})
+
+		expectStartKey()
+		_, err := storage.Query(ctx, "PK#1", nil, dynamorm.QueryStartFrom(cursor))
+		require.NoError(t, err)
+	})
+
+	t.Run("should resume query by GSI1 from cursor", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo)
+		cursor := cursorFor(t, func() (dynamorm.QueryInterface, error) {
+			return storage.QueryGSI1(ctx, "GSI1PK#1", nil)
+		})
+
+		expectStartKey()
+		_, err := storage.QueryGSI1(ctx, "GSI1PK#1", nil, dynamorm.QueryStartFrom(cursor))
+		require.NoError(t, err)
+
+		_, err = storage.Query(ctx, "PK#1", nil, dynamorm.QueryStartFrom(cursor))
+		require.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+	})
+
+	t.Run("should resume signed scan from cursor", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithCursorSecret([]byte("secret")))
+
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(&dynamodb.ScanOutput{LastEvaluatedKey: lastKey}, nil)
+		q, err := storage.Scan(ctx)
+		require.NoError(t, err)
+		cursor, err := q.Cursor()
+		require.NoError(t, err)
+
+		dynamo.EXPECT().
+			Scan(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				require.Equal(t, lastKey, input.ExclusiveStartKey)
+				return &dynamodb.ScanOutput{}, nil
+			})
+		_, err = storage.Scan(ctx, dynamorm.ScanStartFrom(cursor))
+		require.NoError(t, err)
+
+		_, err = storage.ScanGSI1(ctx, dynamorm.ScanStartFrom(cursor))
+		require.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+	})
+
+	t.Run("should reject cursor from another partition", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo)
+		cursor := cursorFor(t, func() (dynamorm.QueryInterface, error) {
+			return storage.Query(ctx, "PK#1", nil)
+		})
+
+		_, err := storage.Query(ctx, "PK#2", nil, dynamorm.QueryStartFrom(cursor))
+		require.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+	})
+
+	t.Run("should resume query of number partition from cursor", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithKeySchema(dynamorm.KeySchema{PKType: types.ScalarAttributeTypeN}))
+		numberKey := map[string]types.AttributeValue{
+			"PK": &types.AttributeValueMemberN{Value: "7"},
+			"SK": &types.AttributeValueMemberS{Value: "SK#1"},
+		}
+
+		dynamo.EXPECT().Query(ctx, gomock.Any()).Return(&dynamodb.QueryOutput{LastEvaluatedKey: numberKey}, nil)
+		q, err := storage.Query(ctx, "007", nil)
+		require.NoError(t, err)
+		cursor, err := q.Cursor()
+		require.NoError(t, err)
+
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, numberKey, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{}, nil
+			})
+		_, err = storage.Query(ctx, "007", nil, dynamorm.QueryStartFrom(cursor))
+		require.NoError(t, err)
+
+		_, err = storage.Query(ctx, "70", nil, dynamorm.QueryStartFrom(cursor))
+		require.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+	})
+
+	t.Run("should reject cursor from another table", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo)
+		cursor := cursorFor(t, func() (dynamorm.QueryInterface, error) {
+			return storage.Query(ctx, "PK#1", nil)
+		})
+
+		other := dynamorm.NewStorage("OtherTable", dynamo)
+		_, err := other.Query(ctx, "PK#1", nil, dynamorm.QueryStartFrom(cursor))
+		require.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+	})
+
+	t.Run("should reject unsigned or tampered cursor", func(t *testing.T) {
+		unsigned := dynamorm.NewStorage("TestTable", dynamo)
+		storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithCursorSecret([]byte("secret")))
+
+		cursor := cursorFor(t, func() (dynamorm.QueryInterface, error) {
+			return unsigned.Query(ctx, "PK#1", nil)
+		})
+		_, err := storage.Query(ctx, "PK#1", nil, dynamorm.QueryStartFrom(cursor))
+		require.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+
+		signed := cursorFor(t, func() (dynamorm.QueryInterface, error) {
+			return storage.Query(ctx, "PK#1", nil)
+		})
+		_, err = storage.Query(ctx, "PK#1", nil, dynamorm.QueryStartFrom("f"+signed[1:]))
+		require.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+
+		other := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithCursorSecret([]byte("other")))
+		_, err = other.Query(ctx, "PK#1", nil, dynamorm.QueryStartFrom(signed))
+		require.ErrorIs(t, err, dynamorm.ErrInvalidCursor)
+	})
+
+	t.Run("should reject malformed cursor", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo)
+
+		for _, cursor := range []string{"!", "bm90IGpzb24", "e30", `eyJ0IjoiVGVzdFRhYmxlIiwiayI6eyJQSyI6e319fQ`} {
+			_, err := storage.Scan(ctx, dynamorm.ScanStartFrom(cursor))
+			require.ErrorIs(t, err, dynamorm.ErrInvalidCursor, cursor)
+		}
+	})
+}
+
+func TestStorageCount(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo)
+	ctx := context.TODO()
+
+	lastKey := map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "PK#1"}}
+
+	t.Run("should count query across pages", func(t *testing.T) {
+		filter := expression.Name("Status").Equal(expression.Value("paid"))
+
+		gomock.InOrder(
+			dynamo.EXPECT().
+				Query(ctx, gomock.Any()).
+				DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+					require.Equal(t, types.SelectCount, input.Select)
+					require.NotNil(t, input.KeyConditionExpression)
+					require.Equal(t, "#0 = :0", aws.ToString(input.FilterExpression))
+					require.ElementsMatch(t, []string{"PK", "SK", "Status"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+					return &dynamodb.QueryOutput{Count: 2, ScannedCount: 3, LastEvaluatedKey: lastKey}, nil
+				}),
+			dynamo.EXPECT().
+				Query(ctx, gomock.Any()).
+				DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+					require.Equal(t, lastKey, input.ExclusiveStartKey)
+					require.Equal(t, types.SelectCount, input.Select)
+					return &dynamodb.QueryOutput{Count: 1, ScannedCount: 4}, nil
+				}),
+		)
+
+		count, err := storage.CountQuery(ctx, "CUSTOMER#1", dynamorm.SkBeginsWith("ORDER#"), dynamorm.QueryFilter(filter))
+		require.NoError(t, err)
+		require.Equal(t, dynamorm.CountOutput{Count: 3, ScannedCount: 7}, count)
+	})
+
+	t.Run("should count scan across pages", func(t *testing.T) {
+		gomock.InOrder(
+			dynamo.EXPECT().
+				Scan(ctx, &dynamodb.ScanInput{
+					TableName: aws.String("TestTable"),
+					Select:    types.SelectCount,
+				}).
+				Return(&dynamodb.ScanOutput{Count: 5, ScannedCount: 5, LastEvaluatedKey: lastKey}, nil),
+			dynamo.EXPECT().
+				Scan(ctx, gomock.Any()).
+				Return(&dynamodb.ScanOutput{Count: 2, ScannedCount: 2}, nil),
+		)
+
+		count, err := storage.CountScan(ctx)
+		require.NoError(t, err)
+		require.Equal(t, dynamorm.CountOutput{Count: 7, ScannedCount: 7}, count)
+	})
+
+	t.Run("should ignore max items", func(t *testing.T) {
+		gomock.InOrder(
+			dynamo.EXPECT().
+				Query(ctx, gomock.Any()).
+				Return(&dynamodb.QueryOutput{Count: 2, ScannedCount: 2, LastEvaluatedKey: lastKey}, nil),
+			dynamo.EXPECT().
+				Query(ctx, gomock.Any()).
+				Return(&dynamodb.QueryOutput{Count: 1, ScannedCount: 1}, nil),
+			dynamo.EXPECT().
+				Scan(ctx, gomock.Any()).
+				Return(&dynamodb.ScanOutput{Count: 4, ScannedCount: 4}, nil),
+		)
+
+		count, err := storage.CountQuery(ctx, "CUSTOMER#1", nil, dynamorm.QueryMaxItems(1))
+		require.NoError(t, err)
+		require.Equal(t, dynamorm.CountOutput{Count: 3, ScannedCount: 3}, count)
+
+		count, err = storage.CountScan(ctx, dynamorm.ScanMaxItems(1))
+		require.NoError(t, err)
+		require.Equal(t, dynamorm.CountOutput{Count: 4, ScannedCount: 4}, count)
+	})
+
+	t.Run("should return client error", func(t *testing.T) {
+		dynamo.EXPECT().Query(ctx, gomock.Any()).Return(nil, assert.AnError)
+
+		_, err := storage.CountQuery(ctx, "CUSTOMER#1", nil)
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+
+		gomock.InOrder(
+			dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(&dynamodb.ScanOutput{Count: 5, LastEvaluatedKey: lastKey}, nil),
+			dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(nil, assert.AnError),
+		)
+
+		_, err = storage.CountScan(ctx)
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(nil, assert.AnError)
+
+		_, err = storage.CountScan(ctx)
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+	})
+}
+
+func TestStorageMaxItems(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo)
+	ctx := context.TODO()
+
+	item := func(i int) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"PK":     &types.AttributeValueMemberS{Value: "PK#1"},
+			"SK":     &types.AttributeValueMemberS{Value: fmt.Sprintf("SK#%d", i)},
+			"GSI1PK": &types.AttributeValueMemberS{Value: "GSI1PK#1"},
+			"GSI1SK": &types.AttributeValueMemberS{Value: fmt.Sprintf("GSI1SK#%d", i)},
+			"Email":  &types.AttributeValueMemberS{Value: fmt.Sprintf("usr%d@go.dev", i)},
+		}
+	}
+	lastKey := func(i int) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: fmt.Sprintf("LEK#%d", i)}}
+	}
+
+	t.Run("should fetch pages until max items are collected", func(t *testing.T) {
+		gomock.InOrder(
+			dynamo.EXPECT().Query(ctx, gomock.Any()).Return(&dynamodb.QueryOutput{
+				ScannedCount:     2,
+				LastEvaluatedKey: lastKey(1),
+			}, nil),
+			dynamo.EXPECT().Query(ctx, gomock.Any()).
+				DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+					require.Equal(t, lastKey(1), input.ExclusiveStartKey)
+					return &dynamodb.QueryOutput{
+						Items:            []map[string]types.AttributeValue{item(1), item(2)},
+						ScannedCount:     2,
+						LastEvaluatedKey: lastKey(2),
+					}, nil
+				}),
+			dynamo.EXPECT().Query(ctx, gomock.Any()).Return(&dynamodb.QueryOutput{
+				Items:            []map[string]types.AttributeValue{item(3), item(4), item(5)},
+				ScannedCount:     3,
+				LastEvaluatedKey: lastKey(3),
+			}, nil),
+		)
+
+		query, err := storage.Query(ctx, "PK#1", nil, dynamorm.QueryLimit(2), dynamorm.QueryMaxItems(4))
+		require.NoError(t, err)
+		require.Equal(t, int32(4), query.Count())
+		require.Equal(t, int32(7), query.ScannedCount())
+
+		var emails []string
+		for query.NextPage(ctx) {
+			for query.Next() {
+				e := &TestEntity{}
+				require.NoError(t, query.Decode(e))
+				emails = append(emails, e.Email)
+			}
+		}
+		require.NoError(t, query.Error())
+		require.Equal(t, []string{"usr1@go.dev", "usr2@go.dev", "usr3@go.dev", "usr4@go.dev"}, emails)
+
+		cursor, err := query.Cursor()
+		require.NoError(t, err)
+
+		// Resume right after the 4th item
+		dynamo.EXPECT().Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "PK#1"},
+					"SK": &types.AttributeValueMemberS{Value: "SK#4"},
+				}, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item(5)}}, nil
+			})
+
+		query, err = storage.Query(ctx, "PK#1", nil, dynamorm.QueryMaxItems(4), dynamorm.QueryStartFrom(cursor))
+		require.NoError(t, err)
+		require.Equal(t, int32(1), query.Count())
+
+		cursor, err = query.Cursor()
+		require.NoError(t, err)
+		require.Empty(t, cursor)
+	})
+
+	t.Run("should keep last evaluated key on page boundary", func(t *testing.T) {
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(&dynamodb.ScanOutput{
+			Items:            []map[string]types.AttributeValue{item(1), item(2)},
+			LastEvaluatedKey: lastKey(1),
+		}, nil)
+
+		query, err := storage.Scan(ctx, dynamorm.ScanMaxItems(2))
+		require.NoError(t, err)
+		require.Equal(t, int32(2), query.Count())
+		require.True(t, query.NextPage(ctx))
+		require.False(t, query.NextPage(ctx))
+
+		cursor, err := query.Cursor()
+		require.NoError(t, err)
+
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				require.Equal(t, lastKey(1), input.ExclusiveStartKey)
+				return &dynamodb.ScanOutput{}, nil
+			})
+
+		_, err = storage.Scan(ctx, dynamorm.ScanStartFrom(cursor))
+		require.NoError(t, err)
+	})
+
+	t.Run("should include index key when truncating GSI scan", func(t *testing.T) {
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(&dynamodb.ScanOutput{
+			Items:            []map[string]types.AttributeValue{item(1), item(2)},
+			LastEvaluatedKey: lastKey(1),
+		}, nil)
+
+		query, err := storage.ScanGSI1(ctx, dynamorm.ScanMaxItems(1))
+		require.NoError(t, err)
+		require.Equal(t, int32(1), query.Count())
+
+		cursor, err := query.Cursor()
+		require.NoError(t, err)
+
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				require.Equal(t, map[string]types.AttributeValue{
+					"PK":     &types.AttributeValueMemberS{Value: "PK#1"},
+					"SK":     &types.AttributeValueMemberS{Value: "SK#1"},
+					"GSI1PK": &types.AttributeValueMemberS{Value: "GSI1PK#1"},
+					"GSI1SK": &types.AttributeValueMemberS{Value: "GSI1SK#1"},
+				}, input.ExclusiveStartKey)
+				return &dynamodb.ScanOutput{}, nil
+			})
+
+		_, err = storage.ScanGSI1(ctx, dynamorm.ScanStartFrom(cursor))
+		require.NoError(t, err)
+	})
+
+	t.Run("should return client error", func(t *testing.T) {
+		gomock.InOrder(
+			dynamo.EXPECT().Query(ctx, gomock.Any()).Return(&dynamodb.QueryOutput{LastEvaluatedKey: lastKey(1)}, nil),
+			dynamo.EXPECT().Query(ctx, gomock.Any()).Return(nil, assert.AnError),
+		)
+
+		query, err := storage.Query(ctx, "PK#1", nil, dynamorm.QueryMaxItems(1))
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+		require.Nil(t, query)
+
+		gomock.InOrder(
+			dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(&dynamodb.ScanOutput{LastEvaluatedKey: lastKey(1)}, nil),
+			dynamo.EXPECT().Scan(ctx, gomock.Any()).Return(nil, assert.AnError),
+		)
+
+		query, err = storage.Scan(ctx, dynamorm.ScanMaxItems(1))
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+		require.Nil(t, query)
+	})
+}
+
+func TestStorageConsumedCapacity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	enc := NewMockEncoderInterface(ctrl)
+	enc.EXPECT().Encode(gomock.Any()).Return(map[string]types.AttributeValue{}, nil).AnyTimes()
+	dec := NewMockDecoderInterface(ctrl)
+	dec.EXPECT().Decode(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	e := NewMockEntity(ctrl)
+	e.EXPECT().PkSk().Return("PK#1", "SK#1").AnyTimes()
+	e.EXPECT().GSI1().Return("", "").AnyTimes()
+	e.EXPECT().GSI2().Return("", "").AnyTimes()
+	e.EXPECT().BeforeSave().Return(nil).AnyTimes()
+
+	capacity := func(units float64) *types.ConsumedCapacity {
+		return &types.ConsumedCapacity{
+			TableName:     aws.String("TestTable"),
+			CapacityUnits: aws.Float64(units),
+		}
+	}
+	item := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "PK#1"},
+		"SK": &types.AttributeValueMemberS{Value: "SK#1"},
+	}
+	indexes := types.ReturnConsumedCapacityIndexes
+
+	t.Run("should not request capacity by default", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithConsumedCapacity("invalid"))
+
+		dynamo.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Empty(t, input.ReturnConsumedCapacity)
+				return &dynamodb.QueryOutput{}, nil
+			})
+
+		query, err := storage.Query(context.TODO(), "PK#1", nil)
+		require.NoError(t, err)
+		require.Nil(t, query.ConsumedCapacity())
+		require.Equal(t, types.ConsumedCapacity{}, storage.ConsumedCapacity().Total())
+	})
+
+	t.Run("should report capacity of every operation", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo,
+			dynamorm.WithEncoder(enc),
+			dynamorm.WithDecoder(dec),
+			dynamorm.WithConsumedCapacity(indexes),
+		)
+
+		dynamo.EXPECT().
+			PutItem(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, indexes, input.ReturnConsumedCapacity)
+				return &dynamodb.PutItemOutput{ConsumedCapacity: capacity(1)}, nil
+			})
+		dynamo.EXPECT().
+			GetItem(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+				require.Equal(t, indexes, input.ReturnConsumedCapacity)
+				return &dynamodb.GetItemOutput{Item: item, ConsumedCapacity: capacity(2)}, nil
+			})
+		dynamo.EXPECT().
+			BatchGetItem(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.BatchGetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+				require.Equal(t, indexes, input.ReturnConsumedCapacity)
+				return &dynamodb.BatchGetItemOutput{
+					Responses:        map[string][]map[string]types.AttributeValue{"TestTable": {item}},
+					ConsumedCapacity: []types.ConsumedCapacity{*capacity(4)},
+				}, nil
+			})
+		dynamo.EXPECT().
+			BatchWriteItem(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+				require.Equal(t, indexes, input.ReturnConsumedCapacity)
+				return &dynamodb.BatchWriteItemOutput{ConsumedCapacity: []types.ConsumedCapacity{*capacity(8)}}, nil
+			})
+		dynamo.EXPECT().
+			UpdateItem(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Equal(t, indexes, input.ReturnConsumedCapacity)
+				return &dynamodb.UpdateItemOutput{ConsumedCapacity: capacity(16)}, nil
+			})
+		dynamo.EXPECT().
+			DeleteItem(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+				require.Equal(t, indexes, input.ReturnConsumedCapacity)
+				return &dynamodb.DeleteItemOutput{ConsumedCapacity: capacity(32)}, nil
+			})
+		dynamo.EXPECT().
+			TransactWriteItems(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				require.Equal(t, indexes, input.ReturnConsumedCapacity)
+				return &dynamodb.TransactWriteItemsOutput{ConsumedCapacity: []types.ConsumedCapacity{*capacity(64)}}, nil
+			})
+
+		// Capacity of writes attributed to a request
+		requestCapacity := &dynamorm.ConsumedCapacity{}
+		ctx := dynamorm.WithCapacity(context.TODO(), requestCapacity)
+
+		require.NoError(t, storage.Save(ctx, e))
+		require.NoError(t, storage.Get(context.TODO(), e))
+		require.NoError(t, storage.BatchGet(context.TODO(), e))
+		require.NoError(t, storage.BatchSave(ctx, e))
+		require.NoError(t, storage.Update(ctx, e, expression.Set(expression.Name("Attr"), expression.Value(1))))
+		require.NoError(t, storage.Remove(ctx, e))
+
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddRemove(e))
+		require.NoError(t, tx.Execute(ctx))
+
+		require.Equal(t, 1.0+8+16+32+64, *requestCapacity.Total().CapacityUnits)
+		require.Equal(t, 127.0, *storage.ConsumedCapacity().Total().CapacityUnits)
+	})
+
+	t.Run("should report capacity of queries and scans", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithConsumedCapacity(indexes))
+		lastKey := map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "PK#1"}}
+
+		gomock.InOrder(
+			dynamo.EXPECT().
+				Query(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+					require.Equal(t, indexes, input.ReturnConsumedCapacity)
+					return &dynamodb.QueryOutput{ConsumedCapacity: capacity(1), LastEvaluatedKey: lastKey}, nil
+				}),
+			dynamo.EXPECT().
+				Query(gomock.Any(), gomock.Any()).
+				Return(&dynamodb.QueryOutput{ConsumedCapacity: capacity(2)}, nil),
+		)
+
+		query, err := storage.Query(context.TODO(), "PK#1", nil)
+		require.NoError(t, err)
+		require.Equal(t, capacity(1), query.ConsumedCapacity())
+
+		require.True(t, query.NextPage(context.TODO()))
+		require.True(t, query.NextPage(context.TODO()))
+		require.Equal(t, capacity(2), query.ConsumedCapacity())
+
+		gomock.InOrder(
+			dynamo.EXPECT().
+				Scan(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+					require.Equal(t, indexes, input.ReturnConsumedCapacity)
+					return &dynamodb.ScanOutput{ConsumedCapacity: capacity(4), LastEvaluatedKey: lastKey}, nil
+				}),
+			dynamo.EXPECT().
+				Scan(gomock.Any(), gomock.Any()).
+				Return(&dynamodb.ScanOutput{ConsumedCapacity: capacity(8)}, nil),
+		)
+
+		// Capacity of all pages merged by ScanMaxItems
+		query, err = storage.Scan(context.TODO(), dynamorm.ScanMaxItems(10))
+		require.NoError(t, err)
+		require.Equal(t, capacity(12), query.ConsumedCapacity())
+
+		require.Equal(t, 15.0, *storage.ConsumedCapacity().Total().CapacityUnits)
+	})
+}
+
+func TestStorageVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo)
+	ctx := context.TODO()
+
+	allOld := types.ReturnValuesOnConditionCheckFailureAllOld
+	version := func(v string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"Version": &types.AttributeValueMemberN{Value: v},
+		}
+	}
+
+	t.Run("should save new entity", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, "attribute_not_exists (#0)", aws.ToString(input.ConditionExpression))
+				require.Equal(t, map[string]string{"#0": "PK"}, input.ExpressionAttributeNames)
+				require.Equal(t, &types.AttributeValueMemberN{Value: "1"}, input.Item["Version"])
+				require.Equal(t, allOld, input.ReturnValuesOnConditionCheckFailure)
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		e := &VersionedEntity{ID: "1"}
+		require.NoError(t, storage.Save(ctx, e))
+		require.Equal(t, int64(1), e.Ver)
+	})
+
+	t.Run("should save entity with expected version and condition", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, "(#0 = :0) AND (attribute_exists (#1))", aws.ToString(input.ConditionExpression))
+				require.Equal(t, map[string]string{"#0": "Version", "#1": "Name"}, input.ExpressionAttributeNames)
+				require.Equal(t, map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberN{Value: "3"},
+				}, input.ExpressionAttributeValues)
+				require.Equal(t, &types.AttributeValueMemberN{Value: "4"}, input.Item["Version"])
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		e := &VersionedEntity{ID: "1", Ver: 3}
+		require.NoError(t, storage.Save(ctx, e, dynamorm.SaveCondition(expression.AttributeExists(expression.Name("Name")))))
+		require.Equal(t, int64(4), e.Ver)
+	})
+
+	t.Run("should return ErrVersionConflict on save", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			Return(nil, &types.ConditionalCheckFailedException{Item: version("4")})
+
+		e := &VersionedEntity{ID: "1", Ver: 3}
+		err := storage.Save(ctx, e)
+		require.ErrorIs(t, err, dynamorm.ErrVersionConflict)
+		require.Equal(t, int64(3), e.Ver)
+
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			Return(nil, &types.ConditionalCheckFailedException{Item: version("1")})
+
+		e = &VersionedEntity{ID: "1"}
+		err = storage.Save(ctx, e)
+		require.ErrorIs(t, err, dynamorm.ErrVersionConflict)
+		require.Equal(t, int64(0), e.Ver)
+	})
+
+	t.Run("should return client error when other condition fails", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			Return(nil, &types.ConditionalCheckFailedException{Item: version("3")})
+
+		e := &VersionedEntity{ID: "1", Ver: 3}
+		err := storage.Save(ctx, e, dynamorm.SaveCondition(expression.AttributeExists(expression.Name("Name"))))
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+		require.NotErrorIs(t, err, dynamorm.ErrVersionConflict)
+		require.Equal(t, int64(3), e.Ver)
+	})
+
+	t.Run("should update entity with expected version", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Contains(t, aws.ToString(input.UpdateExpression), "ADD")
+				require.Contains(t, aws.ToString(input.ConditionExpression), "AND")
+				require.ElementsMatch(t, []string{"Name", "Version"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				require.Equal(t, allOld, input.ReturnValuesOnConditionCheckFailure)
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		e := &VersionedEntity{ID: "1", Ver: 2}
+		err := storage.Update(ctx, e,
+			expression.Set(expression.Name("Name"), expression.Value("John")),
+			dynamorm.UpdateCondition(expression.AttributeExists(expression.Name("Name"))),
+		)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), e.Ver)
+	})
+
+	t.Run("should update entity without version unconditionally", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.NotContains(t, aws.ToString(input.UpdateExpression), "ADD")
+				require.NotContains(t, slices.Collect(maps.Values(input.ExpressionAttributeNames)), dynamorm.VersionAttribute)
+				require.Nil(t, input.ConditionExpression)
+				require.Empty(t, input.ReturnValuesOnConditionCheckFailure)
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		e := &VersionedEntity{ID: "1"}
+		err := storage.Update(ctx, e, expression.Set(expression.Name("Name"), expression.Value("John")))
+		require.NoError(t, err)
+		require.Equal(t, int64(0), e.Ver, "the stored version is left unchanged")
+	})
+
+	t.Run("should return ErrVersionConflict on update", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			Return(nil, &types.ConditionalCheckFailedException{})
+
+		e := &VersionedEntity{ID: "1", Ver: 2}
+		err := storage.Update(ctx, e, expression.Set(expression.Name("Name"), expression.Value("John")))
+		require.ErrorIs(t, err, dynamorm.ErrVersionConflict)
+		require.Equal(t, int64(2), e.Ver)
+	})
+
+	t.Run("should remove entity with expected version", func(t *testing.T) {
+		dynamo.EXPECT().
+			DeleteItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+				require.Equal(t, "#0 = :0", aws.ToString(input.ConditionExpression))
+				require.Equal(t, map[string]string{"#0": "Version"}, input.ExpressionAttributeNames)
+				require.Equal(t, allOld, input.ReturnValuesOnConditionCheckFailure)
+				return &dynamodb.DeleteItemOutput{}, nil
+			})
+
+		e := &VersionedEntity{ID: "1", Ver: 2}
+		require.NoError(t, storage.Remove(ctx, e))
+		require.Equal(t, int64(2), e.Ver)
+
+		dynamo.EXPECT().
+			DeleteItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+				require.Nil(t, input.ConditionExpression)
+				return &dynamodb.DeleteItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Remove(ctx, &VersionedEntity{ID: "1"}))
+	})
+
+	t.Run("should return ErrVersionConflict on remove", func(t *testing.T) {
+		dynamo.EXPECT().
+			DeleteItem(ctx, gomock.Any()).
+			Return(nil, &types.ConditionalCheckFailedException{Item: version("3")})
+
+		err := storage.Remove(ctx, &VersionedEntity{ID: "1", Ver: 2})
+		require.ErrorIs(t, err, dynamorm.ErrVersionConflict)
+	})
+}
+
+func TestStorageTimestamps(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	created := now.Add(-time.Hour)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	enc := NewMockEncoderInterface(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithEncoder(enc),
+		dynamorm.WithClock(func() time.Time { return now }),
+		dynamorm.WithBatchRetry(0, 0),
+	)
+	ctx := context.TODO()
+
+	t.Run("should set timestamps on save", func(t *testing.T) {
+		e := &TimestampedEntity{ID: "1"}
+		enc.EXPECT().
+			Encode(e).
+			DoAndReturn(func(in interface{}) (map[string]types.AttributeValue, error) {
+				require.Equal(t, now, in.(*TimestampedEntity).CreatedAt)
+				require.Equal(t, now, in.(*TimestampedEntity).UpdatedAt)
+				return map[string]types.AttributeValue{}, nil
+			})
+		dynamo.EXPECT().PutItem(ctx, gomock.Any()).Return(&dynamodb.PutItemOutput{}, nil)
+
+		require.NoError(t, storage.Save(ctx, e))
+	})
+
+	t.Run("should keep creation time on save", func(t *testing.T) {
+		e1 := &TimestampedEntity{ID: "1", CreatedAt: created}
+		e2 := &TimestampedEntity{ID: "2"}
+		enc.EXPECT().Encode(gomock.Any()).Return(map[string]types.AttributeValue{}, nil).Times(2)
+		dynamo.EXPECT().BatchWriteItem(ctx, gomock.Any()).Return(&dynamodb.BatchWriteItemOutput{}, nil)
+
+		require.NoError(t, storage.BatchSave(ctx, e1, e2))
+		require.Equal(t, created, e1.CreatedAt)
+		require.Equal(t, now, e1.UpdatedAt)
+		require.Equal(t, now, e2.CreatedAt)
+		require.Equal(t, now, e2.UpdatedAt)
+	})
+
+	t.Run("should set timestamps on update", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Contains(t, aws.ToString(input.UpdateExpression), "if_not_exists")
+				require.ElementsMatch(t, []string{"Name", "UpdatedAt", "CreatedAt"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				require.Len(t, input.ExpressionAttributeValues, 3)
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		e := &TimestampedEntity{ID: "1", CreatedAt: created}
+		require.NoError(t, storage.Update(ctx, e, expression.Set(expression.Name("Name"), expression.Value("John"))))
+		require.Equal(t, created, e.CreatedAt)
+		require.Equal(t, now, e.UpdatedAt)
+	})
+
+	t.Run("should set timestamps in transaction", func(t *testing.T) {
+		save := &TimestampedEntity{ID: "1"}
+		up := &TimestampedEntity{ID: "2", CreatedAt: created}
+
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				require.Contains(t, aws.ToString(input.TransactItems[1].Update.UpdateExpression), "if_not_exists")
+				require.ElementsMatch(t, []string{"Name", "UpdatedAt", "CreatedAt"}, slices.Collect(maps.Values(input.TransactItems[1].Update.ExpressionAttributeNames)))
+				require.Equal(t, created, up.UpdatedAt, "should not be touched before execution")
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+
+		enc.EXPECT().Encode(save).Return(map[string]types.AttributeValue{}, nil)
+		up.UpdatedAt = created
+
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddSave(save))
+		require.NoError(t, tx.AddUpdate(up, expression.Set(expression.Name("Name"), expression.Value("John"))))
+		require.Equal(t, now, save.CreatedAt)
+		require.Equal(t, now, save.UpdatedAt)
+
+		require.NoError(t, tx.Execute(ctx))
+		require.Equal(t, created, up.CreatedAt)
+		require.Equal(t, now, up.UpdatedAt)
+	})
+}
+
+func TestStorageTTL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	past := &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)}
+	future := &types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(time.Hour).Unix(), 10)}
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithClock(func() time.Time { return now }),
+		dynamorm.WithTTL(""),
+	)
+	ctx := context.TODO()
+
+	t.Run("should write expiry on save", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, future, input.Item[dynamorm.TTLAttribute])
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, &ExpirableEntity{ID: "1", Expires: now.Add(time.Hour)}))
+	})
+
+	t.Run("should not write zero expiry", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.NotContains(t, input.Item, dynamorm.TTLAttribute)
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, &ExpirableEntity{ID: "1"}))
+	})
+
+	t.Run("should write expiry in transaction", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				require.Equal(t, future, input.TransactItems[0].Put.Item[dynamorm.TTLAttribute])
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddSave(&ExpirableEntity{ID: "1", Expires: now.Add(time.Hour)}))
+		require.NoError(t, tx.Execute(ctx))
+	})
+
+	t.Run("should patch expiry", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Contains(t, slices.Collect(maps.Values(input.ExpressionAttributeNames)), dynamorm.TTLAttribute)
+				require.Contains(t, slices.Collect(maps.Values(input.ExpressionAttributeValues)), types.AttributeValue(future))
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Patch(ctx, &ExpirableEntity{ID: "1", Expires: now.Add(time.Hour)}, dynamorm.TTLAttribute))
+	})
+
+	t.Run("should not get expired item", func(t *testing.T) {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"ID":                  &types.AttributeValueMemberS{Value: "1"},
+				dynamorm.TTLAttribute: past,
+			}}, nil)
+
+		err := storage.Get(ctx, &ExpirableEntity{ID: "1"})
+		require.ErrorIs(t, err, dynamorm.ErrEntityNotFound)
+	})
+
+	t.Run("should get unexpired item", func(t *testing.T) {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"ID":                  &types.AttributeValueMemberS{Value: "1"},
+				dynamorm.TTLAttribute: future,
+			}}, nil)
+
+		e := &ExpirableEntity{}
+		require.NoError(t, storage.Get(ctx, e))
+		require.Equal(t, "1", e.ID)
+	})
+
+	t.Run("should not batch get expired item", func(t *testing.T) {
+		dynamo.EXPECT().
+			BatchGetItem(ctx, gomock.Any()).
+			Return(&dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]types.AttributeValue{
+				"TestTable": {
+					{"PK": &types.AttributeValueMemberS{Value: "PK#1"}, "SK": &types.AttributeValueMemberS{Value: "SK"}, "ID": &types.AttributeValueMemberS{Value: "1"}, dynamorm.TTLAttribute: past},
+					{"PK": &types.AttributeValueMemberS{Value: "PK#2"}, "SK": &types.AttributeValueMemberS{Value: "SK"}, "ID": &types.AttributeValueMemberS{Value: "2"}, dynamorm.TTLAttribute: future},
+				},
+			}}, nil)
+
+		e1, e2 := &ExpirableEntity{ID: "1"}, &ExpirableEntity{ID: "2"}
+		err := storage.BatchGet(ctx, e1, e2)
+		require.ErrorIs(t, err, dynamorm.ErrEntityNotFound)
+
+		var notFound *dynamorm.NotFoundError
+		require.ErrorAs(t, err, &notFound)
+		require.Equal(t, []dynamorm.Entity{e1}, notFound.Entities)
+	})
+
+	t.Run("should filter out expired items of query", func(t *testing.T) {
+		filter := expression.Name("Status").Equal(expression.Value("paid"))
+
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, "(#0 = :0) AND ((attribute_not_exists (#1)) OR (#1 > :1))", aws.ToString(input.FilterExpression))
+				require.Equal(t, dynamorm.TTLAttribute, input.ExpressionAttributeNames["#1"])
+				require.Equal(t, past, input.ExpressionAttributeValues[":1"])
+				return &dynamodb.QueryOutput{}, nil
+			})
+
+		_, err := storage.Query(ctx, "PK", nil, dynamorm.QueryFilter(filter))
+		require.NoError(t, err)
+	})
+
+	t.Run("should filter out expired items of scan", func(t *testing.T) {
+		dynamo.EXPECT().
+			Scan(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				require.Equal(t, "(attribute_not_exists (#0)) OR (#0 > :0)", aws.ToString(input.FilterExpression))
+				require.Equal(t, map[string]string{"#0": dynamorm.TTLAttribute}, input.ExpressionAttributeNames)
+				require.Equal(t, map[string]types.AttributeValue{":0": past}, input.ExpressionAttributeValues)
+				return &dynamodb.ScanOutput{}, nil
+			})
+
+		_, err := storage.Scan(ctx, dynamorm.ScanIncludeDeleted())
+		require.NoError(t, err)
+	})
+
+	t.Run("should enable ttl", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+				TableName: aws.String("TestTable"),
+				TimeToLiveSpecification: &types.TimeToLiveSpecification{
+					AttributeName: aws.String(dynamorm.TTLAttribute),
+					Enabled:       aws.Bool(true),
+				},
+			}).
+			Return(&dynamodb.UpdateTimeToLiveOutput{}, nil)
+
+		require.NoError(t, storage.EnableTTL(ctx))
+	})
+
+	t.Run("should return client error when enabling ttl fails", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateTimeToLive(ctx, gomock.Any()).
+			Return(nil, assert.AnError)
+
+		err := storage.EnableTTL(ctx)
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+		require.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("should write expiry under custom attribute", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithTTL("expires_at"))
+
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, future, input.Item["expires_at"])
+				require.NotContains(t, input.Item, dynamorm.TTLAttribute)
+				return &dynamodb.PutItemOutput{}, nil
+			})
+		dynamo.EXPECT().
+			UpdateTimeToLive(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateTimeToLiveInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateTimeToLiveOutput, error) {
+				require.Equal(t, "expires_at", aws.ToString(input.TimeToLiveSpecification.AttributeName))
+				return &dynamodb.UpdateTimeToLiveOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, &ExpirableEntity{ID: "1", Expires: now.Add(time.Hour)}))
+		require.NoError(t, storage.EnableTTL(ctx))
+	})
+
+	t.Run("should ignore expiry when ttl is disabled", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo,
+			dynamorm.WithClock(func() time.Time { return now }),
+		)
+
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.NotContains(t, input.Item, dynamorm.TTLAttribute)
+				return &dynamodb.PutItemOutput{}, nil
+			})
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"ID":                  &types.AttributeValueMemberS{Value: "1"},
+				dynamorm.TTLAttribute: past,
+			}}, nil)
+
+		require.NoError(t, storage.Save(ctx, &ExpirableEntity{ID: "1", Expires: now.Add(time.Hour)}))
+
+		e := &ExpirableEntity{ID: "1"}
+		require.NoError(t, storage.Get(ctx, e))
+		require.ErrorIs(t, storage.EnableTTL(ctx), dynamorm.ErrTTLDisabled)
+	})
+}
+
+func TestStorageSoftRemove(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	deletedAt := &types.AttributeValueMemberS{Value: now.Format(time.RFC3339)}
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithClock(func() time.Time { return now }),
+		dynamorm.WithSoftDelete(""),
+	)
+	ctx := context.TODO()
+
+	t.Run("should mark item as deleted and remove index keys", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Equal(t, map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "PK#1"},
+					"SK": &types.AttributeValueMemberS{Value: "SK#1"},
+				}, input.Key)
+				require.Contains(t, aws.ToString(input.UpdateExpression), "REMOVE")
+				require.Contains(t, aws.ToString(input.ConditionExpression), "attribute_exists")
+				require.ElementsMatch(t, []string{"DeletedAt", "GSI1PK", "GSI1SK", "GSI2PK", "GSI2SK", "PK"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.SoftRemove(ctx, &AccountEntity{ID: "1"}))
+	})
+
+	t.Run("should restore item and its index keys", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Contains(t, aws.ToString(input.UpdateExpression), "REMOVE")
+				require.Contains(t, aws.ToString(input.ConditionExpression), "attribute_exists")
+				require.ElementsMatch(t, []string{"DeletedAt", "GSI1PK", "GSI1SK", "GSI2PK", "GSI2SK", "PK"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				require.ElementsMatch(t, []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "GSI1PK#1"},
+					&types.AttributeValueMemberS{Value: "GSI1SK#1"},
+					&types.AttributeValueMemberS{Value: "GSI2PK#1"},
+					&types.AttributeValueMemberS{Value: "GSI2SK#1"},
+				}, slices.Collect(maps.Values(input.ExpressionAttributeValues)))
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Restore(ctx, &AccountEntity{ID: "1"}))
+	})
+
+	t.Run("should return not found when item does not exist", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			Return(nil, &types.ConditionalCheckFailedException{}).
+			Times(2)
+
+		require.ErrorIs(t, storage.SoftRemove(ctx, &AccountEntity{ID: "1"}), dynamorm.ErrEntityNotFound)
+		require.ErrorIs(t, storage.Restore(ctx, &AccountEntity{ID: "1"}), dynamorm.ErrEntityNotFound)
+	})
+
+	t.Run("should return client error", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			Return(nil, assert.AnError)
+
+		err := storage.SoftRemove(ctx, &AccountEntity{ID: "1"})
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+		require.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("should not get deleted item", func(t *testing.T) {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"ID":                        &types.AttributeValueMemberS{Value: "1"},
+				dynamorm.DeletedAtAttribute: deletedAt,
+			}}, nil).
+			Times(2)
+
+		require.ErrorIs(t, storage.Get(ctx, &AccountEntity{ID: "1"}), dynamorm.ErrEntityNotFound)
+
+		e := &AccountEntity{ID: "1"}
+		require.NoError(t, storage.Get(ctx, e, dynamorm.GetIncludeDeleted()))
+	})
+
+	t.Run("should not batch get deleted item", func(t *testing.T) {
+		dynamo.EXPECT().
+			BatchGetItem(ctx, gomock.Any()).
+			Return(&dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]types.AttributeValue{
+				"TestTable": {
+					{"PK": &types.AttributeValueMemberS{Value: "PK#1"}, "SK": &types.AttributeValueMemberS{Value: "SK#1"}, "ID": &types.AttributeValueMemberS{Value: "1"}, dynamorm.DeletedAtAttribute: deletedAt},
+					{"PK": &types.AttributeValueMemberS{Value: "PK#2"}, "SK": &types.AttributeValueMemberS{Value: "SK#2"}, "ID": &types.AttributeValueMemberS{Value: "2"}, dynamorm.DeletedAtAttribute: &types.AttributeValueMemberNULL{Value: true}},
+				},
+			}}, nil)
+
+		e1, e2 := &AccountEntity{ID: "1"}, &AccountEntity{ID: "2"}
+		err := storage.BatchGet(ctx, e1, e2)
+		require.ErrorIs(t, err, dynamorm.ErrEntityNotFound)
+
+		var notFound *dynamorm.NotFoundError
+		require.ErrorAs(t, err, &notFound)
+		require.Equal(t, []dynamorm.Entity{e1}, notFound.Entities)
+	})
+
+	t.Run("should leave deleted items out of query and scan", func(t *testing.T) {
+		const filter = "(attribute_not_exists (#0)) OR (attribute_type (#0, :0))"
+
+		gomock.InOrder(
+			dynamo.EXPECT().
+				Query(ctx, gomock.Any()).
+				DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+					require.Equal(t, filter, aws.ToString(input.FilterExpression))
+					require.Equal(t, dynamorm.DeletedAtAttribute, input.ExpressionAttributeNames["#0"])
+					require.Equal(t, &types.AttributeValueMemberS{Value: "NULL"}, input.ExpressionAttributeValues[":0"])
+					return &dynamodb.QueryOutput{}, nil
+				}),
+			dynamo.EXPECT().
+				Query(ctx, gomock.Any()).
+				DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+					require.NotContains(t, slices.Collect(maps.Values(input.ExpressionAttributeNames)), dynamorm.DeletedAtAttribute)
+					return &dynamodb.QueryOutput{}, nil
+				}),
+			dynamo.EXPECT().
+				Scan(ctx, gomock.Any()).
+				DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+					require.Equal(t, filter, aws.ToString(input.FilterExpression))
+					return &dynamodb.ScanOutput{}, nil
+				}),
+			dynamo.EXPECT().
+				Scan(ctx, gomock.Any()).
+				DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+					require.NotContains(t, slices.Collect(maps.Values(input.ExpressionAttributeNames)), dynamorm.DeletedAtAttribute)
+					return &dynamodb.ScanOutput{}, nil
+				}),
+		)
+
+		_, err := storage.Query(ctx, "PK", nil)
+		require.NoError(t, err)
+		_, err = storage.Query(ctx, "PK", nil, dynamorm.QueryIncludeDeleted())
+		require.NoError(t, err)
+		_, err = storage.Scan(ctx)
+		require.NoError(t, err)
+		_, err = storage.Scan(ctx, dynamorm.ScanIncludeDeleted())
+		require.NoError(t, err)
+	})
+
+	t.Run("should mark item as deleted with custom attribute", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo,
+			dynamorm.WithClock(func() time.Time { return now }),
+			dynamorm.WithSoftDelete("deleted_at"),
+		)
+
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Contains(t, slices.Collect(maps.Values(input.ExpressionAttributeNames)), "deleted_at")
+				require.NotContains(t, slices.Collect(maps.Values(input.ExpressionAttributeNames)), dynamorm.DeletedAtAttribute)
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+		dynamo.EXPECT().
+			Scan(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				require.Equal(t, "(attribute_not_exists (#0)) OR (attribute_type (#0, :0))", aws.ToString(input.FilterExpression))
+				require.Equal(t, map[string]string{"#0": "deleted_at"}, input.ExpressionAttributeNames)
+				return &dynamodb.ScanOutput{}, nil
+			})
+
+		require.NoError(t, storage.SoftRemove(ctx, &AccountEntity{ID: "1"}))
+
+		_, err := storage.Scan(ctx)
+		require.NoError(t, err)
+	})
+
+	t.Run("should ignore deleted items when soft delete is disabled", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo)
+
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				"ID":                        &types.AttributeValueMemberS{Value: "1"},
+				dynamorm.DeletedAtAttribute: deletedAt,
+			}}, nil)
+		dynamo.EXPECT().
+			Scan(ctx, &dynamodb.ScanInput{TableName: aws.String("TestTable")}).
+			Return(&dynamodb.ScanOutput{}, nil)
+
+		require.NoError(t, storage.Get(ctx, &AccountEntity{ID: "1"}))
+
+		_, err := storage.Scan(ctx)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, storage.SoftRemove(ctx, &AccountEntity{ID: "1"}), dynamorm.ErrSoftDeleteDisabled)
+		require.ErrorIs(t, storage.Restore(ctx, &AccountEntity{ID: "1"}), dynamorm.ErrSoftDeleteDisabled)
+	})
+}
+
+func TestStoragePatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	enc := NewMockEncoderInterface(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithEncoder(enc))
+	ctx := context.TODO()
+
+	t.Run("should write named attributes and index keys", func(t *testing.T) {
+		e := &AccountEntity{ID: "1", Name: "John"}
+		enc.EXPECT().Encode(e).Return(map[string]types.AttributeValue{
+			"ID":   &types.AttributeValueMemberS{Value: "1"},
+			"Name": &types.AttributeValueMemberS{Value: "John"},
+		}, nil)
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Equal(t, map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "PK#1"},
+					"SK": &types.AttributeValueMemberS{Value: "SK#1"},
+				}, input.Key)
+				require.Contains(t, aws.ToString(input.UpdateExpression), "REMOVE")
+				require.ElementsMatch(t, []string{"Name", "Status", "GSI1PK", "GSI1SK", "GSI2PK", "GSI2SK"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				require.ElementsMatch(t, []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "John"},
+					&types.AttributeValueMemberS{Value: "GSI1PK#1"},
+					&types.AttributeValueMemberS{Value: "GSI1SK#1"},
+					&types.AttributeValueMemberS{Value: "GSI2PK#1"},
+					&types.AttributeValueMemberS{Value: "GSI2SK#1"},
+				}, slices.Collect(maps.Values(input.ExpressionAttributeValues)))
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Patch(ctx, e, "Name", "Status", "Name", "PK"))
+	})
+
+	t.Run("should not write managed attributes", func(t *testing.T) {
+		e := &VersionedEntity{ID: "1", Name: "John", Ver: 2}
+		enc.EXPECT().Encode(e).Return(map[string]types.AttributeValue{
+			"Name":    &types.AttributeValueMemberS{Value: "John"},
+			"Version": &types.AttributeValueMemberN{Value: "2"},
+		}, nil)
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Contains(t, aws.ToString(input.UpdateExpression), "ADD")
+				require.ElementsMatch(t, []string{"Name", "Version", "GSI1PK", "GSI1SK", "GSI2PK", "GSI2SK"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Patch(ctx, e, "Name", "Version"))
+		require.Equal(t, int64(3), e.Ver)
+	})
+
+	t.Run("should return encode error", func(t *testing.T) {
+		e := &AccountEntity{ID: "1"}
+		enc.EXPECT().Encode(e).Return(nil, assert.AnError)
+
+		err := storage.Patch(ctx, e, "Name")
+		require.ErrorIs(t, err, dynamorm.ErrEntityEncode)
+	})
+
+	t.Run("should return before save error", func(t *testing.T) {
+		e := NewMockEntity(ctrl)
+		e.EXPECT().BeforeSave().Return(assert.AnError)
+
+		err := storage.Patch(ctx, e, "Name")
+		require.ErrorIs(t, err, dynamorm.ErrEntityBeforeSave)
+	})
+
+	t.Run("should patch in transaction", func(t *testing.T) {
+		e := &AccountEntity{ID: "1", Status: "shipped"}
+		enc.EXPECT().Encode(e).Return(map[string]types.AttributeValue{
+			"Status": &types.AttributeValueMemberS{Value: "shipped"},
+		}, nil)
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				update := input.TransactItems[0].Update
+				require.NotNil(t, update)
+				require.ElementsMatch(t, []string{"Status", "GSI1PK", "GSI1SK", "GSI2PK", "GSI2SK"}, slices.Collect(maps.Values(update.ExpressionAttributeNames)))
+				require.Contains(t, slices.Collect(maps.Values(update.ExpressionAttributeValues)), types.AttributeValue(&types.AttributeValueMemberS{Value: "shipped"}))
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddPatch(e, "Status"))
+		require.NoError(t, tx.Execute(ctx))
+	})
+}
+
+func TestStorageSaveChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo)
+	ctx := context.TODO()
+
+	stored := func() map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"PK":    &types.AttributeValueMemberS{Value: "PK#1"},
+			"SK":    &types.AttributeValueMemberS{Value: "SK"},
+			"ID":    &types.AttributeValueMemberS{Value: "1"},
+			"Name":  &types.AttributeValueMemberS{Value: "John"},
+			"Email": &types.AttributeValueMemberS{Value: "john@doe.com"},
+		}
+	}
+	load := func(t *testing.T) *TrackedEntity {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: stored()}, nil)
+
+		e := &TrackedEntity{ID: "1"}
+		require.NoError(t, storage.Get(ctx, e))
+		require.Equal(t, stored(), e.Snapshot())
+		return e
+	}
+
+	t.Run("should write changed attributes only", func(t *testing.T) {
+		e := load(t)
+		e.Name = "Jane"
+		e.Email = ""
+
+		updated := stored()
+		updated["Name"] = &types.AttributeValueMemberS{Value: "Jane"}
+		delete(updated, "Email")
+
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Equal(t, map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "PK#1"},
+					"SK": &types.AttributeValueMemberS{Value: "SK"},
+				}, input.Key)
+				require.Contains(t, aws.ToString(input.UpdateExpression), "REMOVE")
+				require.Contains(t, aws.ToString(input.ConditionExpression), "attribute_exists")
+				require.Equal(t, dynamorm.ALL_NEW, input.ReturnValues)
+				require.ElementsMatch(t, []string{"Name", "Email", "PK"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				require.ElementsMatch(t, []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "Jane"},
+				}, slices.Collect(maps.Values(input.ExpressionAttributeValues)))
+				return &dynamodb.UpdateItemOutput{Attributes: updated}, nil
+			})
+
+		require.NoError(t, storage.SaveChanges(ctx, e))
+		require.Equal(t, updated, e.Snapshot())
+	})
+
+	t.Run("should not send request without changes", func(t *testing.T) {
+		e := load(t)
+
+		require.NoError(t, storage.SaveChanges(ctx, e))
+	})
+
+	t.Run("should save entity without snapshot", func(t *testing.T) {
+		dynamo.EXPECT().PutItem(ctx, gomock.Any()).Return(&dynamodb.PutItemOutput{}, nil)
+
+		e := &TrackedEntity{ID: "1", Name: "John"}
+		require.NoError(t, storage.SaveChanges(ctx, e))
+		require.Equal(t, map[string]types.AttributeValue{
+			"PK":   &types.AttributeValueMemberS{Value: "PK#1"},
+			"SK":   &types.AttributeValueMemberS{Value: "SK"},
+			"ID":   &types.AttributeValueMemberS{Value: "1"},
+			"Name": &types.AttributeValueMemberS{Value: "John"},
+		}, e.Snapshot())
+
+		require.NoError(t, storage.SaveChanges(ctx, e))
+	})
+
+	t.Run("should return not found when item was removed", func(t *testing.T) {
+		e := load(t)
+		e.Name = "Jane"
+
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			Return(nil, &types.ConditionalCheckFailedException{})
+
+		require.ErrorIs(t, storage.SaveChanges(ctx, e), dynamorm.ErrEntityNotFound)
+	})
+
+	t.Run("should snapshot decoded query items", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			Return(&dynamodb.QueryOutput{Count: 1, Items: []map[string]types.AttributeValue{stored()}}, nil)
+
+		query, err := storage.Query(ctx, "PK#1", nil)
+		require.NoError(t, err)
+		require.True(t, query.Next())
+
+		e := &TrackedEntity{}
+		require.NoError(t, query.Decode(e))
+		require.Equal(t, stored(), e.Snapshot())
+	})
+}
+
+func TestStorageKeySchema(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithKeySchema(dynamorm.KeySchema{
+			PK:   "pk",
+			SK:   "sk",
+			GSI1: dynamorm.IndexSchema{Name: "gsi1", PK: "gsi1_pk", SK: "gsi1_sk"},
+			GSI2: dynamorm.IndexSchema{PK: "gsi2_pk"},
+		}),
+	)
+	ctx := context.TODO()
+
+	key := map[string]types.AttributeValue{
+		"pk": &types.AttributeValueMemberS{Value: "PK#1"},
+		"sk": &types.AttributeValueMemberS{Value: "SK#1"},
+	}
+
+	t.Run("should save with mapped attributes", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.ElementsMatch(t, []string{"ID", "Name", "pk", "sk", "gsi1_pk", "gsi1_sk", "gsi2_pk", "GSI2SK"}, slices.Collect(maps.Keys(input.Item)))
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, &AccountEntity{ID: "1"}))
+	})
+
+	t.Run("should get, update and remove by mapped key", func(t *testing.T) {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+				require.Equal(t, key, input.Key)
+				return &dynamodb.GetItemOutput{Item: key}, nil
+			})
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Equal(t, key, input.Key)
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+		dynamo.EXPECT().
+			DeleteItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+				require.Equal(t, key, input.Key)
+				return &dynamodb.DeleteItemOutput{}, nil
+			})
+
+		e := &AccountEntity{ID: "1"}
+		require.NoError(t, storage.Get(ctx, e))
+		require.NoError(t, storage.Update(ctx, e, expression.Set(expression.Name("Name"), expression.Value("John"))))
+		require.NoError(t, storage.Remove(ctx, e))
+	})
+
+	t.Run("should not decode key attributes into fields", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo,
+			dynamorm.WithKeySchema(dynamorm.KeySchema{PK: "id", SK: "name"}),
+		)
+		item := map[string]types.AttributeValue{
+			"id":   &types.AttributeValueMemberS{Value: "PK#1"},
+			"name": &types.AttributeValueMemberS{Value: "SK#1"},
+			"ID":   &types.AttributeValueMemberS{Value: "1"},
+			"Name": &types.AttributeValueMemberS{Value: "John"},
+		}
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: item}, nil).
+			Times(20)
+
+		// The decoder matches field names case-insensitively, in map order.
+		for range 20 {
+			e := &AccountEntity{ID: "1"}
+			require.NoError(t, storage.Get(ctx, e))
+			require.Equal(t, &AccountEntity{ID: "1", Name: "John"}, e)
+		}
+	})
+
+	t.Run("should query mapped index", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, "gsi1", aws.ToString(input.IndexName))
+				require.ElementsMatch(t, []string{"gsi1_pk", "gsi1_sk"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				return &dynamodb.QueryOutput{}, nil
+			})
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, "GSI2", aws.ToString(input.IndexName))
+				require.ElementsMatch(t, []string{"gsi2_pk"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				return &dynamodb.QueryOutput{}, nil
+			})
+
+		_, err := storage.QueryGSI1(ctx, "GSI1PK#1", dynamorm.SkBeginsWith("GSI1SK"))
+		require.NoError(t, err)
+		_, err = storage.QueryGSI2(ctx, "GSI2PK#1", nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("should resume mapped index after max items", func(t *testing.T) {
+		item := func(id string) map[string]types.AttributeValue {
+			return map[string]types.AttributeValue{
+				"pk":      &types.AttributeValueMemberS{Value: "PK#" + id},
+				"sk":      &types.AttributeValueMemberS{Value: "SK#" + id},
+				"gsi1_pk": &types.AttributeValueMemberS{Value: "GSI1PK"},
+				"gsi1_sk": &types.AttributeValueMemberS{Value: "GSI1SK#" + id},
+				"ID":      &types.AttributeValueMemberS{Value: id},
+			}
+		}
+		dynamo.EXPECT().
+			Scan(ctx, gomock.Any()).
+			Return(&dynamodb.ScanOutput{Count: 2, Items: []map[string]types.AttributeValue{item("1"), item("2")}}, nil)
+
+		query, err := storage.ScanGSI1(ctx, dynamorm.ScanMaxItems(1))
+		require.NoError(t, err)
+
+		cursor, err := query.Cursor()
+		require.NoError(t, err)
+
+		dynamo.EXPECT().
+			Scan(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				require.Equal(t, "gsi1", aws.ToString(input.IndexName))
+				lastKey := item("1")
+				delete(lastKey, "ID")
+				require.Equal(t, lastKey, input.ExclusiveStartKey)
+				return &dynamodb.ScanOutput{}, nil
+			})
+
+		_, err = storage.ScanGSI1(ctx, dynamorm.ScanStartFrom(cursor))
+		require.NoError(t, err)
+	})
+
+	t.Run("should use mapped attributes in transaction", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				require.Contains(t, input.TransactItems[0].Put.Item, "gsi1_pk")
+				require.Equal(t, key, input.TransactItems[1].Delete.Key)
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddSave(&AccountEntity{ID: "1"}))
+		require.NoError(t, tx.AddRemove(&AccountEntity{ID: "1"}))
+		require.NoError(t, tx.Execute(ctx))
+	})
+}
+
+func TestStorageNoSortKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithKeySchema(dynamorm.KeySchema{PK: "id", NoSortKey: true}),
+	)
+	ctx := context.TODO()
+
+	key := func(id string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"id": &types.AttributeValueMemberS{Value: "SESSION#" + id},
+		}
+	}
+
+	t.Run("should save without sort key", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, map[string]types.AttributeValue{
+					"id": &types.AttributeValueMemberS{Value: "SESSION#1"},
+					"ID": &types.AttributeValueMemberS{Value: "1"},
+				}, input.Item)
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, &SessionEntity{ID: "1"}))
+	})
+
+	t.Run("should get, update and remove by partition key", func(t *testing.T) {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+				require.Equal(t, key("1"), input.Key)
+				return &dynamodb.GetItemOutput{Item: key("1")}, nil
+			})
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Equal(t, key("1"), input.Key)
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+		dynamo.EXPECT().
+			DeleteItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+				require.Equal(t, key("1"), input.Key)
+				return &dynamodb.DeleteItemOutput{}, nil
+			})
+
+		e := &SessionEntity{ID: "1"}
+		require.NoError(t, storage.Get(ctx, e))
+		require.NoError(t, storage.Update(ctx, e, expression.Set(expression.Name("Name"), expression.Value("John"))))
+		require.NoError(t, storage.Remove(ctx, e))
+	})
+
+	t.Run("should ignore sort key of entity", func(t *testing.T) {
+		dynamo.EXPECT().
+			DeleteItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+				require.Equal(t, map[string]types.AttributeValue{
+					"id": &types.AttributeValueMemberS{Value: "PK#1"},
+				}, input.Key)
+				return &dynamodb.DeleteItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Remove(ctx, &AccountEntity{ID: "1"}))
+	})
+
+	t.Run("should batch get by partition key", func(t *testing.T) {
+		dynamo.EXPECT().
+			BatchGetItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.BatchGetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+				require.Equal(t, []map[string]types.AttributeValue{key("1"), key("2")}, input.RequestItems["TestTable"].Keys)
+				return &dynamodb.BatchGetItemOutput{
+					Responses: map[string][]map[string]types.AttributeValue{
+						"TestTable": {key("1")},
+					},
+				}, nil
+			})
+
+		err := storage.BatchGet(ctx, &SessionEntity{ID: "1"}, &SessionEntity{ID: "2"})
+		var notFound *dynamorm.NotFoundError
+		require.ErrorAs(t, err, &notFound)
+		require.Equal(t, []dynamorm.Entity{&SessionEntity{ID: "2"}}, notFound.Entities)
+	})
+
+	t.Run("should query by partition key only", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.ElementsMatch(t, []string{"id"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				return &dynamodb.QueryOutput{}, nil
+			})
+
+		_, err := storage.Query(ctx, "SESSION#1", nil)
+		require.NoError(t, err)
+
+		_, err = storage.Query(ctx, "SESSION#1", dynamorm.SkBeginsWith("SK"))
+		require.ErrorIs(t, err, dynamorm.ErrNoSortKey)
+	})
+
+	t.Run("should use partition key in transaction", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				require.Equal(t, key("1"), input.TransactItems[0].Delete.Key)
+				require.Equal(t, key("2"), input.TransactItems[1].ConditionCheck.Key)
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddRemove(&SessionEntity{ID: "1"}))
+		require.NoError(t, tx.AddConditionCheck(&SessionEntity{ID: "2"}, expression.AttributeExists(expression.Name("ID"))))
+		require.NoError(t, tx.Execute(ctx))
+	})
+}
+
+func TestStorageIndexes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithIndexes(
+			dynamorm.IndexSchema{Name: "GSI3", PK: "GSI3PK", SK: "GSI3SK"},
+			dynamorm.IndexSchema{Name: "LSI1", PK: "LSI1PK", SK: "LSI1SK", Local: true},
+		),
+		dynamorm.WithKeySchema(dynamorm.KeySchema{}),
+	)
+	ctx := context.TODO()
+
+	t.Run("should save keys of registered indexes", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.ElementsMatch(t, []string{"ID", "Status", "PK", "SK", "GSI3PK", "GSI3SK", "LSI1SK"}, slices.Collect(maps.Keys(input.Item)))
+				require.Equal(t, &types.AttributeValueMemberS{Value: "STATUS#paid"}, input.Item["GSI3PK"])
+				require.Equal(t, &types.AttributeValueMemberS{Value: "STATUS#paid"}, input.Item["LSI1SK"])
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, &OrderEntity{ID: "1", Status: "paid"}))
+	})
+
+	t.Run("should query global index", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, "GSI3", aws.ToString(input.IndexName))
+				require.ElementsMatch(t, []string{"GSI3PK", "GSI3SK"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				return &dynamodb.QueryOutput{}, nil
+			})
+
+		_, err := storage.QueryIndex(ctx, "GSI3", "STATUS#paid", dynamorm.SkBeginsWith("ORDER#"))
+		require.NoError(t, err)
+	})
+
+	t.Run("should query local index by table partition key", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, "LSI1", aws.ToString(input.IndexName))
+				require.ElementsMatch(t, []string{"PK", "LSI1SK"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				return &dynamodb.QueryOutput{}, nil
+			})
+
+		_, err := storage.QueryIndex(ctx, "LSI1", "ORDER#1", dynamorm.SkEQ("STATUS#paid"))
+		require.NoError(t, err)
+	})
+
+	t.Run("should scan registered index", func(t *testing.T) {
+		dynamo.EXPECT().
+			Scan(ctx, &dynamodb.ScanInput{
+				TableName: aws.String("TestTable"),
+				IndexName: aws.String("GSI3"),
+			}).
+			Return(&dynamodb.ScanOutput{}, nil)
+
+		_, err := storage.ScanIndex(ctx, "GSI3")
+		require.NoError(t, err)
+	})
+
+	t.Run("should return unknown index error", func(t *testing.T) {
+		_, err := storage.QueryIndex(ctx, "Unknown", "PK", nil)
+		require.ErrorIs(t, err, dynamorm.ErrUnknownIndex)
+
+		_, err = storage.ScanIndex(ctx, "Unknown")
+		require.ErrorIs(t, err, dynamorm.ErrUnknownIndex)
+	})
+
+	t.Run("should remove keys of all indexes on soft remove", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", dynamo,
+			dynamorm.WithIndexes(
+				dynamorm.IndexSchema{Name: "GSI3", PK: "GSI3PK", SK: "GSI3SK"},
+				dynamorm.IndexSchema{Name: "LSI1", PK: "LSI1PK", SK: "LSI1SK", Local: true},
+			),
+			dynamorm.WithSoftDelete(""),
+		)
+
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.ElementsMatch(t, []string{"DeletedAt", "GSI1PK", "GSI1SK", "GSI2PK", "GSI2SK", "GSI3PK", "GSI3SK", "LSI1SK", "PK"}, slices.Collect(maps.Values(input.ExpressionAttributeNames)))
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.SoftRemove(ctx, &OrderEntity{ID: "1"}))
+	})
+}
+
+func TestStorageKeyTypes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithKeySchema(dynamorm.KeySchema{
+			SKType: types.ScalarAttributeTypeN,
+			GSI1:   dynamorm.IndexSchema{PKType: types.ScalarAttributeTypeB},
+		}),
+	)
+	ctx := context.TODO()
+
+	key := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "STREAM#1"},
+		"SK": &types.AttributeValueMemberN{Value: "42"},
+	}
+
+	t.Run("should save typed keys", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, key["PK"], input.Item["PK"])
+				require.Equal(t, key["SK"], input.Item["SK"])
+				require.Equal(t, &types.AttributeValueMemberB{Value: []byte("abc")}, input.Item["GSI1PK"])
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, &EventEntity{Stream: "1", Sequence: 42, Checksum: "abc"}))
+	})
+
+	t.Run("should get, update and remove by typed keys", func(t *testing.T) {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+				require.Equal(t, key, input.Key)
+				return &dynamodb.GetItemOutput{Item: key}, nil
+			})
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Equal(t, key, input.Key)
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+		dynamo.EXPECT().
+			DeleteItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+				require.Equal(t, key, input.Key)
+				return &dynamodb.DeleteItemOutput{}, nil
+			})
+
+		e := &EventEntity{Stream: "1", Sequence: 42}
+		require.NoError(t, storage.Get(ctx, e))
+		require.NoError(t, storage.Update(ctx, e, expression.Set(expression.Name("Checksum"), expression.Value("abc"))))
+		require.NoError(t, storage.Remove(ctx, e))
+	})
+
+	t.Run("should batch remove by typed keys", func(t *testing.T) {
+		dynamo.EXPECT().
+			BatchWriteItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+				require.Equal(t, key, input.RequestItems["TestTable"][0].DeleteRequest.Key)
+				return &dynamodb.BatchWriteItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.BatchRemove(ctx, &EventEntity{Stream: "1", Sequence: 42}))
+	})
+
+	t.Run("should batch get by number keys in any format", func(t *testing.T) {
+		dynamo.EXPECT().
+			BatchGetItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.BatchGetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+				require.Len(t, input.RequestItems["TestTable"].Keys, 2)
+				return &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]types.AttributeValue{
+					"TestTable": {
+						{"PK": key["PK"], "SK": &types.AttributeValueMemberN{Value: "7"}, "Checksum": &types.AttributeValueMemberS{Value: "abc"}},
+						{"PK": key["PK"], "SK": key["SK"], "Checksum": &types.AttributeValueMemberS{Value: "def"}},
+					},
+				}}, nil
+			})
+
+		e1 := &PaddedEventEntity{EventEntity{Stream: "1", Sequence: 7}}
+		e2 := &EventEntity{Stream: "1", Sequence: 7}
+		e3 := &PaddedEventEntity{EventEntity{Stream: "1", Sequence: 42}}
+		require.NoError(t, storage.BatchGet(ctx, e1, e2, e3))
+		require.Equal(t, "abc", e1.Checksum)
+		require.Equal(t, "abc", e2.Checksum)
+		require.Equal(t, "def", e3.Checksum)
+	})
+
+	t.Run("should query by typed keys", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.ElementsMatch(t, []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "STREAM#1"},
+					&types.AttributeValueMemberN{Value: "10"},
+				}, slices.Collect(maps.Values(input.ExpressionAttributeValues)))
+				return &dynamodb.QueryOutput{}, nil
+			})
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, "GSI1", *input.IndexName)
+				require.ElementsMatch(t, []types.AttributeValue{
+					&types.AttributeValueMemberB{Value: []byte("abc")},
+				}, slices.Collect(maps.Values(input.ExpressionAttributeValues)))
+				return &dynamodb.QueryOutput{}, nil
+			})
+
+		_, err := storage.Query(ctx, "STREAM#1", dynamorm.SkGT(10))
+		require.NoError(t, err)
+
+		_, err = storage.QueryIndex(ctx, "GSI1", "abc", nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("should use typed keys in transaction", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				require.Equal(t, key, input.TransactItems[0].Delete.Key)
+				require.Equal(t, key["SK"], input.TransactItems[1].Put.Item["SK"])
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddRemove(&EventEntity{Stream: "1", Sequence: 42}))
+		require.NoError(t, tx.AddSave(&EventEntity{Stream: "1", Sequence: 42}))
+		require.NoError(t, tx.Execute(ctx))
+	})
+}
+
+func TestStorageEntityTypes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo,
+		dynamorm.WithEntityType("account", &AccountEntity{}),
+		dynamorm.WithEntityType("order", &OrderEntity{}),
+		dynamorm.WithEntityType("user", dynamorm.Keyed(&TaggedUser{})),
+	)
+	ctx := context.TODO()
+
+	t.Run("should save type name", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, &types.AttributeValueMemberS{Value: "account"}, input.Item[dynamorm.TypeAttribute])
+				return &dynamodb.PutItemOutput{}, nil
+			})
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.NotContains(t, input.Item, dynamorm.TypeAttribute)
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, &AccountEntity{ID: "1"}))
+		require.NoError(t, storage.Save(ctx, &EventEntity{Stream: "1", Sequence: 1}))
+	})
+
+	t.Run("should patch type name", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				require.Contains(t, slices.Collect(maps.Values(input.ExpressionAttributeNames)), dynamorm.TypeAttribute)
+				require.Contains(t, slices.Collect(maps.Values(input.ExpressionAttributeValues)), types.AttributeValue(&types.AttributeValueMemberS{Value: "order"}))
+				return &dynamodb.UpdateItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Patch(ctx, &OrderEntity{ID: "1"}, dynamorm.TypeAttribute))
+	})
+
+	t.Run("should save type name in transaction", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				require.Equal(t, &types.AttributeValueMemberS{Value: "order"}, input.TransactItems[0].Put.Item[dynamorm.TypeAttribute])
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddSave(&OrderEntity{ID: "1"}))
+		require.NoError(t, tx.Execute(ctx))
+	})
+
+	t.Run("should decode any registered type", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			Return(&dynamodb.QueryOutput{
+				Items: []map[string]types.AttributeValue{
+					{
+						dynamorm.TypeAttribute: &types.AttributeValueMemberS{Value: "account"},
+						"ID":                   &types.AttributeValueMemberS{Value: "1"},
+						"Name":                 &types.AttributeValueMemberS{Value: "John"},
+					},
+					{
+						dynamorm.TypeAttribute: &types.AttributeValueMemberS{Value: "order"},
+						"ID":                   &types.AttributeValueMemberS{Value: "2"},
+						"Status":               &types.AttributeValueMemberS{Value: "paid"},
+					},
+					{
+						dynamorm.TypeAttribute: &types.AttributeValueMemberS{Value: "invoice"},
+					},
+					{
+						"ID": &types.AttributeValueMemberS{Value: "3"},
+					},
+				},
+				Count: 4,
+			}, nil)
+
+		query, err := storage.Query(ctx, "PK#1", nil)
+		require.NoError(t, err)
+
+		var entities []dynamorm.Entity
+		for i := 0; query.Next(); i++ {
+			e, err := query.DecodeAny()
+			if i >= 2 {
+				require.ErrorIs(t, err, dynamorm.ErrUnknownEntityType)
+				continue
+			}
+			require.NoError(t, err)
+			entities = append(entities, e)
+		}
+
+		require.Equal(t, []dynamorm.Entity{
+			&AccountEntity{ID: "1", Name: "John"},
+			&OrderEntity{ID: "2", Status: "paid"},
+		}, entities)
+
+		switch e := entities[1].(type) {
+		case *OrderEntity:
+			require.Equal(t, "paid", e.Status)
+		default:
+			t.Fatalf("unexpected type %T", e)
+		}
+	})
+
+	t.Run("should save and decode keyed type", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, &types.AttributeValueMemberS{Value: "user"}, input.Item[dynamorm.TypeAttribute])
+				return &dynamodb.PutItemOutput{}, nil
+			})
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			Return(&dynamodb.QueryOutput{
+				Items: []map[string]types.AttributeValue{
+					{
+						dynamorm.TypeAttribute: &types.AttributeValueMemberS{Value: "user"},
+						"ID":                   &types.AttributeValueMemberS{Value: "1"},
+						"Email":                &types.AttributeValueMemberS{Value: "john@doe.com"},
+					},
+				},
+				Count: 1,
+			}, nil)
+
+		require.NoError(t, storage.Save(ctx, dynamorm.Keyed(&TaggedUser{ID: "1"})))
+
+		query, err := storage.Query(ctx, "USER#1", nil)
+		require.NoError(t, err)
+		require.True(t, query.Next())
+
+		e, err := query.DecodeAny()
+		require.NoError(t, err)
+		require.Equal(t, &TaggedUser{ID: "1", Email: "john@doe.com", Derived: "derived#1"}, dynamorm.Unwrap(e))
+
+		pk, sk := e.PkSk()
+		require.Equal(t, "USER#1", pk)
+		require.Equal(t, "USER", sk)
+	})
+
+	t.Run("should not decode out of range", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			Return(&dynamodb.QueryOutput{}, nil)
+
+		query, err := storage.Query(ctx, "PK#1", nil)
+		require.NoError(t, err)
+
+		_, err = query.DecodeAny()
+		require.ErrorIs(t, err, dynamorm.ErrIndexOutOfRange)
+	})
+}
+
+func TestStorageLoadCollection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo)
+	ctx := context.TODO()
+
+	item := func(sk string, attributes map[string]types.AttributeValue) map[string]types.AttributeValue {
+		attributes["PK"] = &types.AttributeValueMemberS{Value: "CUSTOMER#1"}
+		attributes["SK"] = &types.AttributeValueMemberS{Value: sk}
+		return attributes
+	}
+
+	t.Run("should load collection into bindings", func(t *testing.T) {
+		lastKey := item("ORDER#1", map[string]types.AttributeValue{})
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Nil(t, input.ExclusiveStartKey)
+				require.True(t, aws.ToBool(input.ConsistentRead))
+				require.ElementsMatch(t, []types.AttributeValue{
+					&types.AttributeValueMemberS{Value: "CUSTOMER#1"},
+				}, slices.Collect(maps.Values(input.ExpressionAttributeValues)))
+				return &dynamodb.QueryOutput{
+					Items: []map[string]types.AttributeValue{
+						item("ACCOUNT", map[string]types.AttributeValue{
+							"ID":   &types.AttributeValueMemberS{Value: "1"},
+							"Name": &types.AttributeValueMemberS{Value: "John"},
+						}),
+						item("ORDER#1", map[string]types.AttributeValue{
+							"ID": &types.AttributeValueMemberS{Value: "1"},
+						}),
+					},
+					Count:            2,
+					LastEvaluatedKey: lastKey,
+				}, nil
+			})
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+				require.Equal(t, lastKey, input.ExclusiveStartKey)
+				return &dynamodb.QueryOutput{
+					Items: []map[string]types.AttributeValue{
+						item("ORDER#2", map[string]types.AttributeValue{
+							"ID": &types.AttributeValueMemberS{Value: "2"},
+						}),
+						item("EVENT#1", map[string]types.AttributeValue{
+							dynamorm.TypeAttribute: &types.AttributeValueMemberS{Value: "event"},
+							"Stream":               &types.AttributeValueMemberS{Value: "1"},
+						}),
+						item("ACCOUNT", map[string]types.AttributeValue{
+							"ID": &types.AttributeValueMemberS{Value: "2"},
+						}),
+						item("ADDRESS#1", map[string]types.AttributeValue{}),
+					},
+					Count: 4,
+				}, nil
+			})
+
+		var account AccountEntity
+		var orders []*OrderEntity
+		var events []*EventEntity
+		err := storage.LoadCollection(ctx, "CUSTOMER#1", []dynamorm.Binding{
+			dynamorm.BindPrefix("ACCOUNT", &account),
+			dynamorm.BindPrefix("ORDER#", &orders),
+			dynamorm.BindType("event", &events),
+		}, dynamorm.QueryConsistent(true))
+		require.NoError(t, err)
+
+		require.Equal(t, AccountEntity{ID: "1", Name: "John"}, account)
+		require.Equal(t, []*OrderEntity{{ID: "1"}, {ID: "2"}}, orders)
+		require.Equal(t, []*EventEntity{{Stream: "1"}}, events)
+	})
+
+	t.Run("should return invalid binding", func(t *testing.T) {
+		var orders []OrderEntity
+		var order *OrderEntity
+		for _, target := range []any{nil, orders, &orders, order, &order, &[]*string{}} {
+			err := storage.LoadCollection(ctx, "CUSTOMER#1", []dynamorm.Binding{dynamorm.BindPrefix("ORDER#", target)})
+			require.ErrorIs(t, err, dynamorm.ErrInvalidBinding)
+		}
+	})
+
+	t.Run("should return not found for unbound entity", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			Return(&dynamodb.QueryOutput{
+				Items: []map[string]types.AttributeValue{
+					item("ORDER#1", map[string]types.AttributeValue{
+						"ID": &types.AttributeValueMemberS{Value: "1"},
+					}),
+				},
+				Count: 1,
+			}, nil)
+
+		var account AccountEntity
+		var orders []*OrderEntity
+		var events []*EventEntity
+		err := storage.LoadCollection(ctx, "CUSTOMER#1", []dynamorm.Binding{
+			dynamorm.BindPrefix("ACCOUNT", &account),
+			dynamorm.BindPrefix("ORDER#", &orders),
+			dynamorm.BindType("event", &events),
+		})
+		require.ErrorIs(t, err, dynamorm.ErrEntityNotFound)
+
+		var notFound *dynamorm.NotFoundError
+		require.ErrorAs(t, err, &notFound)
+		require.Equal(t, []dynamorm.Entity{&account}, notFound.Entities)
+		require.Equal(t, []*OrderEntity{{ID: "1"}}, orders)
+		require.Empty(t, events)
+	})
+
+	t.Run("should return client error", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			Return(nil, assert.AnError)
+
+		var orders []*OrderEntity
+		err := storage.LoadCollection(ctx, "CUSTOMER#1", []dynamorm.Binding{dynamorm.BindPrefix("ORDER#", &orders)})
 		require.ErrorIs(t, err, dynamorm.ErrClient)
-		require.Nil(t, query)
+		require.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("should return decode error", func(t *testing.T) {
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			Return(&dynamodb.QueryOutput{
+				Items: []map[string]types.AttributeValue{
+					item("ORDER#1", map[string]types.AttributeValue{
+						"ID": &types.AttributeValueMemberL{},
+					}),
+				},
+				Count: 1,
+			}, nil)
+
+		var orders []*OrderEntity
+		err := storage.LoadCollection(ctx, "CUSTOMER#1", []dynamorm.Binding{dynamorm.BindPrefix("ORDER#", &orders)})
+		require.ErrorIs(t, err, dynamorm.ErrEntityDecode)
+	})
+}
+
+func TestStorageHooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithSoftDelete(""))
+	ctx := context.TODO()
+
+	item := map[string]types.AttributeValue{
+		"PK": &types.AttributeValueMemberS{Value: "HOOK#1"},
+		"SK": &types.AttributeValueMemberS{Value: "HOOK"},
+		"ID": &types.AttributeValueMemberS{Value: "1"},
+	}
+
+	t.Run("should call save hooks", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			Return(&dynamodb.PutItemOutput{}, nil)
+
+		e := &HookEntity{ID: "1"}
+		require.NoError(t, storage.Save(ctx, e))
+		require.Equal(t, []string{"BeforeSave", "AfterSave"}, e.Calls)
+	})
+
+	t.Run("should return after save error", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			Return(&dynamodb.PutItemOutput{}, nil)
+
+		err := storage.Save(ctx, &HookEntity{ID: "1", Fail: "AfterSave"})
+		require.ErrorIs(t, err, dynamorm.ErrEntityAfterSave)
+	})
+
+	t.Run("should not call after save on error", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			Return(nil, assert.AnError)
+
+		e := &HookEntity{ID: "1"}
+		require.ErrorIs(t, storage.Save(ctx, e), assert.AnError)
+		require.Equal(t, []string{"BeforeSave"}, e.Calls)
+	})
+
+	t.Run("should call after load", func(t *testing.T) {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: item}, nil)
+		dynamo.EXPECT().
+			Query(ctx, gomock.Any()).
+			Return(&dynamodb.QueryOutput{Items: []map[string]types.AttributeValue{item}, Count: 1}, nil)
+
+		e := &HookEntity{ID: "1"}
+		require.NoError(t, storage.Get(ctx, e))
+		require.Equal(t, "derived#1", e.Derived)
+		require.Equal(t, []string{"AfterLoad"}, e.Calls)
+
+		query, err := storage.Query(ctx, "HOOK#1", nil)
+		require.NoError(t, err)
+
+		var first HookEntity
+		require.NoError(t, query.First(&first))
+		require.Equal(t, "derived#1", first.Derived)
+	})
+
+	t.Run("should return after load error", func(t *testing.T) {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			Return(&dynamodb.GetItemOutput{Item: item}, nil)
+
+		err := storage.Get(ctx, &HookEntity{ID: "1", Fail: "AfterLoad"})
+		require.ErrorIs(t, err, dynamorm.ErrEntityAfterLoad)
+	})
+
+	t.Run("should call update hooks", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			Return(&dynamodb.UpdateItemOutput{Attributes: item}, nil)
+
+		e := &HookEntity{ID: "1"}
+		err := storage.Update(ctx, e, expression.Set(expression.Name("ID"), expression.Value("1")), dynamorm.UpdateReturnValues(dynamorm.ALL_NEW))
+		require.NoError(t, err)
+		require.Equal(t, []string{"BeforeUpdate", "AfterLoad"}, e.Calls)
+	})
+
+	t.Run("should abort update", func(t *testing.T) {
+		err := storage.Update(ctx, &HookEntity{ID: "1", Fail: "BeforeUpdate"}, expression.Set(expression.Name("ID"), expression.Value("1")))
+		require.ErrorIs(t, err, dynamorm.ErrEntityBeforeUpdate)
+	})
+
+	t.Run("should call remove hooks", func(t *testing.T) {
+		dynamo.EXPECT().
+			DeleteItem(ctx, gomock.Any()).
+			Return(&dynamodb.DeleteItemOutput{}, nil)
+
+		e := &HookEntity{ID: "1"}
+		require.NoError(t, storage.Remove(ctx, e))
+		require.Equal(t, []string{"BeforeRemove", "AfterRemove"}, e.Calls)
+	})
+
+	t.Run("should abort remove", func(t *testing.T) {
+		err := storage.Remove(ctx, &HookEntity{ID: "1", Fail: "BeforeRemove"})
+		require.ErrorIs(t, err, dynamorm.ErrEntityBeforeRemove)
+	})
+
+	t.Run("should call remove hooks on soft remove", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			Return(&dynamodb.UpdateItemOutput{}, nil)
+
+		e := &HookEntity{ID: "1"}
+		require.NoError(t, storage.SoftRemove(ctx, e))
+		require.Equal(t, []string{"BeforeRemove", "BeforeUpdate", "AfterRemove"}, e.Calls)
+	})
+
+	t.Run("should abort soft remove", func(t *testing.T) {
+		err := storage.SoftRemove(ctx, &HookEntity{ID: "1", Fail: "BeforeRemove"})
+		require.ErrorIs(t, err, dynamorm.ErrEntityBeforeRemove)
+	})
+
+	t.Run("should not call after remove on soft remove error", func(t *testing.T) {
+		dynamo.EXPECT().
+			UpdateItem(ctx, gomock.Any()).
+			Return(nil, &types.ConditionalCheckFailedException{})
+
+		e := &HookEntity{ID: "1"}
+		err := storage.SoftRemove(ctx, e)
+		require.ErrorIs(t, err, dynamorm.ErrEntityNotFound)
+		require.Equal(t, []string{"BeforeRemove", "BeforeUpdate"}, e.Calls)
+	})
+
+	t.Run("should call batch hooks on written entities", func(t *testing.T) {
+		dynamo.EXPECT().
+			BatchWriteItem(ctx, gomock.Any()).
+			Return(&dynamodb.BatchWriteItemOutput{}, nil)
+		dynamo.EXPECT().
+			BatchWriteItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.BatchWriteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+				return &dynamodb.BatchWriteItemOutput{
+					UnprocessedItems: map[string][]types.WriteRequest{
+						"TestTable": input.RequestItems["TestTable"][1:],
+					},
+				}, nil
+			})
+
+		e1, e2 := &HookEntity{ID: "1"}, &HookEntity{ID: "2", Fail: "AfterSave"}
+		err := storage.BatchSave(ctx, e1, e2)
+		require.ErrorIs(t, err, dynamorm.ErrEntityAfterSave)
+		require.Equal(t, []string{"BeforeSave", "AfterSave"}, e1.Calls)
+
+		noRetry := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithBatchRetry(0, 0))
+		e3, e4 := &HookEntity{ID: "3"}, &HookEntity{ID: "4"}
+		err = noRetry.BatchRemove(ctx, e3, e4)
+		var batchErr *dynamorm.BatchError
+		require.ErrorAs(t, err, &batchErr)
+		require.Equal(t, []dynamorm.Entity{e4}, batchErr.Entities)
+		require.Equal(t, []string{"BeforeRemove", "AfterRemove"}, e3.Calls)
+		require.Equal(t, []string{"BeforeRemove"}, e4.Calls)
+	})
+
+	t.Run("should abort batch remove", func(t *testing.T) {
+		err := storage.BatchRemove(ctx, &HookEntity{ID: "1"}, &HookEntity{ID: "2", Fail: "BeforeRemove"})
+		require.ErrorIs(t, err, dynamorm.ErrEntityBeforeRemove)
+	})
+
+	t.Run("should call transaction hooks once executed", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			Return(&dynamodb.TransactWriteItemsOutput{}, nil)
+
+		saved, updated, removed := &HookEntity{ID: "1"}, &HookEntity{ID: "2"}, &HookEntity{ID: "3"}
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddSave(saved))
+		require.NoError(t, tx.AddUpdate(updated, expression.Set(expression.Name("ID"), expression.Value("2"))))
+		require.NoError(t, tx.AddRemove(removed))
+		require.Equal(t, []string{"BeforeSave"}, saved.Calls)
+		require.Equal(t, []string{"BeforeRemove"}, removed.Calls)
+
+		require.NoError(t, tx.Execute(ctx))
+		require.Equal(t, []string{"BeforeSave", "AfterSave"}, saved.Calls)
+		require.Equal(t, []string{"BeforeUpdate"}, updated.Calls)
+		require.Equal(t, []string{"BeforeRemove", "AfterRemove"}, removed.Calls)
+	})
+
+	t.Run("should not call transaction after hooks on error", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			Return(nil, assert.AnError)
+
+		e := &HookEntity{ID: "1"}
+		tx := storage.Transaction()
+		require.NoError(t, tx.AddSave(e))
+		require.ErrorIs(t, tx.Execute(ctx), assert.AnError)
+		require.Equal(t, []string{"BeforeSave"}, e.Calls)
+	})
+
+	t.Run("should abort transaction operations", func(t *testing.T) {
+		tx := storage.Transaction()
+		err := tx.AddUpdate(&HookEntity{ID: "1", Fail: "BeforeUpdate"}, expression.Set(expression.Name("ID"), expression.Value("1")))
+		require.ErrorIs(t, err, dynamorm.ErrEntityBeforeUpdate)
+		require.ErrorIs(t, tx.AddRemove(&HookEntity{ID: "1", Fail: "BeforeRemove"}), dynamorm.ErrEntityBeforeRemove)
+	})
+}
+
+func TestStorageValidation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo)
+	ctx := context.TODO()
+
+	t.Run("should save valid entity", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			Return(&dynamodb.PutItemOutput{}, nil)
+
+		require.NoError(t, storage.Save(ctx, &ProfileEntity{ID: "1", Email: "john@doe.com", Age: 150}))
+	})
+
+	t.Run("should return field errors", func(t *testing.T) {
+		err := storage.Save(ctx, &ProfileEntity{ID: "1", Email: "john", Nickname: "jo", Age: 151})
+		require.ErrorIs(t, err, dynamorm.ErrEntityValidation)
+
+		var verr *dynamorm.ValidationError
+		require.ErrorAs(t, err, &verr)
+		require.Equal(t, []dynamorm.FieldError{
+			{Field: "Email", Rule: "email", Message: "must be an email address"},
+			{Field: "Nickname", Rule: "min=3", Message: "must be at least 3"},
+			{Field: "Age", Rule: "max=150", Message: "must be at most 150"},
+		}, verr.Fields)
+
+		err = storage.Save(ctx, &ProfileEntity{ID: "1", Email: "john.doe@example.com"})
+		require.ErrorAs(t, err, &verr)
+		require.Equal(t, []dynamorm.FieldError{
+			{Field: "Email", Rule: "max=16", Message: "must be at most 16"},
+		}, verr.Fields)
+	})
+
+	t.Run("should check required before other rules", func(t *testing.T) {
+		var verr *dynamorm.ValidationError
+		require.ErrorAs(t, storage.Save(ctx, &ProfileEntity{}), &verr)
+		require.Equal(t, []dynamorm.FieldError{
+			{Field: "ID", Rule: "required", Message: "is required"},
+			{Field: "Email", Rule: "required", Message: "is required"},
+		}, verr.Fields)
+	})
+
+	t.Run("should return unknown rules", func(t *testing.T) {
+		var verr *dynamorm.ValidationError
+		require.ErrorAs(t, storage.Save(ctx, dynamorm.Keyed(&TaggedEntity{ID: "1"})), &verr)
+		require.Equal(t, []dynamorm.FieldError{
+			{Field: "Name", Rule: "requird", Message: "has an unknown rule requird"},
+		}, verr.Fields)
+	})
+
+	t.Run("should return validator error", func(t *testing.T) {
+		var verr *dynamorm.ValidationError
+		require.ErrorAs(t, storage.Save(ctx, &ProfileEntity{ID: "1", Email: "john@doe.com", Rejected: true}), &verr)
+		require.Equal(t, []dynamorm.FieldError{{Message: "profile is rejected"}}, verr.Fields)
+	})
+
+	t.Run("should validate before batch save, patch, save changes and transaction", func(t *testing.T) {
+		invalid := &ProfileEntity{ID: "1"}
+
+		require.ErrorIs(t, storage.BatchSave(ctx, invalid), dynamorm.ErrEntityValidation)
+		require.ErrorIs(t, storage.Patch(ctx, invalid, "Email"), dynamorm.ErrEntityValidation)
+		require.ErrorIs(t, storage.SaveChanges(ctx, invalid), dynamorm.ErrEntityValidation)
+
+		tx := storage.Transaction()
+		require.ErrorIs(t, tx.AddSave(invalid), dynamorm.ErrEntityValidation)
+		require.ErrorIs(t, tx.AddPatch(invalid, "Email"), dynamorm.ErrEntityValidation)
+	})
+}
+
+func TestStorageKeyed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo)
+	ctx := context.TODO()
+
+	item := map[string]types.AttributeValue{
+		"PK":     &types.AttributeValueMemberS{Value: "USER#1"},
+		"SK":     &types.AttributeValueMemberS{Value: "USER"},
+		"GSI1PK": &types.AttributeValueMemberS{Value: "USER#EMAIL"},
+		"GSI1SK": &types.AttributeValueMemberS{Value: "john@doe.com"},
+		"ID":     &types.AttributeValueMemberS{Value: "1"},
+		"Email":  &types.AttributeValueMemberS{Value: "john@doe.com"},
+		"Age":    &types.AttributeValueMemberN{Value: "42"},
+	}
+
+	t.Run("should save with key templates", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.Equal(t, item, input.Item)
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, dynamorm.Keyed(&TaggedUser{ID: "1", Email: "john@doe.com", Age: 42})))
+	})
+
+	t.Run("should leave out keys with empty fields", func(t *testing.T) {
+		dynamo.EXPECT().
+			PutItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+				require.NotContains(t, input.Item, "GSI1SK")
+				return &dynamodb.PutItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Save(ctx, dynamorm.Keyed(&TaggedUser{ID: "1"})))
+		require.ErrorIs(t, storage.Save(ctx, dynamorm.Keyed(&TaggedUser{})), dynamorm.ErrEntityPkNotSet)
+	})
+
+	t.Run("should get into struct", func(t *testing.T) {
+		dynamo.EXPECT().
+			GetItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+				require.Equal(t, map[string]types.AttributeValue{
+					"PK": item["PK"],
+					"SK": item["SK"],
+				}, input.Key)
+				return &dynamodb.GetItemOutput{Item: item}, nil
+			})
+
+		user := &TaggedUser{ID: "1"}
+		require.NoError(t, storage.Get(ctx, dynamorm.Keyed(user)))
+		require.Equal(t, &TaggedUser{ID: "1", Email: "john@doe.com", Age: 42, Derived: "derived#1"}, user)
+	})
+
+	t.Run("should format integer fields", func(t *testing.T) {
+		dynamo.EXPECT().
+			DeleteItem(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+				require.Equal(t, map[string]types.AttributeValue{
+					"PK": &types.AttributeValueMemberS{Value: "STREAM#1"},
+					"SK": &types.AttributeValueMemberS{Value: "42"},
+				}, input.Key)
+				return &dynamodb.DeleteItemOutput{}, nil
+			})
+
+		require.NoError(t, storage.Remove(ctx, dynamorm.Keyed(&TaggedEvent{Stream: "1", Sequence: 42})))
+	})
+
+	t.Run("should treat zero fields as missing", func(t *testing.T) {
+		require.ErrorIs(t, storage.Remove(ctx, dynamorm.Keyed(&TaggedEvent{Stream: "1"})), dynamorm.ErrEntitySkNotSet)
+	})
+}
+
+func TestRegisterKeys(t *testing.T) {
+	t.Run("should register valid templates", func(t *testing.T) {
+		require.NoError(t, dynamorm.RegisterKeys(&TaggedUser{}))
+		require.NoError(t, dynamorm.RegisterKeys(&TaggedEvent{}))
+	})
+
+	t.Run("should return invalid key template", func(t *testing.T) {
+		type noPK struct {
+			_  struct{} `dynamorm:"sk=USER"`
+			ID string
+		}
+		type unknownField struct {
+			_ struct{} `dynamorm:"pk=USER#{ID}"`
+		}
+		type unclosed struct {
+			_  struct{} `dynamorm:"pk=USER#{ID"`
+			ID string
+		}
+		type unexpected struct {
+			_  struct{} `dynamorm:"pk=USER#ID}"`
+			ID string
+		}
+		type unsupported struct {
+			_  struct{} `dynamorm:"pk=USER#{ID}"`
+			ID []string
+		}
+		type duplicate struct {
+			_  struct{} `dynamorm:"pk=USER#{ID},pk=USER"`
+			ID string
+		}
+
+		for _, v := range []any{nil, TaggedUser{}, &noPK{}, &unknownField{}, &unclosed{}, &unexpected{}, &unsupported{}, &duplicate{}} {
+			require.ErrorIs(t, dynamorm.RegisterKeys(v), dynamorm.ErrInvalidKeyTemplate, "%T", v)
+		}
+	})
+
+	t.Run("should return invalid key template on use", func(t *testing.T) {
+		storage := dynamorm.NewStorage("TestTable", nil)
+		ctx := context.TODO()
+
+		e := dynamorm.Keyed(&struct{ ID string }{})
+		require.NotPanics(t, func() { e.PkSk() })
+		require.ErrorIs(t, storage.Save(ctx, e), dynamorm.ErrInvalidKeyTemplate)
+		require.ErrorIs(t, storage.Get(ctx, e), dynamorm.ErrInvalidKeyTemplate)
+		require.ErrorIs(t, storage.Remove(ctx, e), dynamorm.ErrInvalidKeyTemplate)
 	})
 }
 
@@ -809,6 +3939,184 @@ func TestStorageScan(t *testing.T) {
 	})
 }
 
+func TestStorageParallelScan(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	storage := dynamorm.NewStorage("TestTable", dynamo)
+	ctx := context.TODO()
+
+	email := func(segment int32, page int) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			"Email": &types.AttributeValueMemberS{Value: fmt.Sprintf("usr%d-%d@go.dev", segment, page)},
+		}
+	}
+
+	// Each segment returns two pages of one item
+	scan := func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+		segment := aws.ToInt32(input.Segment)
+		if input.ExclusiveStartKey == nil {
+			return &dynamodb.ScanOutput{
+				Items:            []map[string]types.AttributeValue{email(segment, 1)},
+				LastEvaluatedKey: map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "PK"}},
+			}, nil
+		}
+		return &dynamodb.ScanOutput{
+			Items: []map[string]types.AttributeValue{email(segment, 2)},
+		}, nil
+	}
+
+	t.Run("should scan all segments", func(t *testing.T) {
+		dynamo.EXPECT().
+			Scan(ctx, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				require.Equal(t, "TestTable", aws.ToString(input.TableName))
+				require.Nil(t, input.IndexName)
+				require.Equal(t, int32(3), aws.ToInt32(input.TotalSegments))
+				return scan(ctx, input, opts...)
+			}).
+			Times(6)
+
+		var emails []string
+		for result := range storage.ParallelScan(ctx, 3) {
+			require.NoError(t, result.Err)
+
+			e := &TestEntity{}
+			require.NoError(t, result.Decode(e))
+			require.Contains(t, e.Email, fmt.Sprintf("usr%d-", result.Segment))
+			emails = append(emails, e.Email)
+		}
+
+		require.ElementsMatch(t, []string{
+			"usr0-1@go.dev", "usr0-2@go.dev",
+			"usr1-1@go.dev", "usr1-2@go.dev",
+			"usr2-1@go.dev", "usr2-2@go.dev",
+		}, emails)
+	})
+
+	t.Run("should scan GSIs with at least one segment", func(t *testing.T) {
+		for index, results := range map[string]func() <-chan dynamorm.ScanResult{
+			"GSI1": func() <-chan dynamorm.ScanResult { return storage.ParallelScanGSI1(ctx, 0) },
+			"GSI2": func() <-chan dynamorm.ScanResult { return storage.ParallelScanGSI2(ctx, 0) },
+		} {
+			dynamo.EXPECT().
+				Scan(ctx, gomock.Any()).
+				DoAndReturn(func(ctx context.Context, input *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+					require.Equal(t, index, aws.ToString(input.IndexName))
+					require.Equal(t, int32(1), aws.ToInt32(input.TotalSegments))
+					return scan(ctx, input, opts...)
+				}).
+				Times(2)
+
+			count := 0
+			for result := range results() {
+				require.NoError(t, result.Err)
+				require.Equal(t, 0, result.Segment)
+				count++
+			}
+			require.Equal(t, 2, count)
+		}
+	})
+
+	t.Run("should report segment errors", func(t *testing.T) {
+		dynamo.EXPECT().
+			Scan(ctx, gomock.Any()).
+			DoAndReturn(func(ctx context.Context, input *dynamodb.ScanInput, opts ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				if aws.ToInt32(input.Segment) == 1 && input.ExclusiveStartKey != nil {
+					return nil, assert.AnError
+				}
+				if aws.ToInt32(input.Segment) == 2 {
+					return nil, assert.AnError
+				}
+				return scan(ctx, input, opts...)
+			}).
+			Times(5)
+
+		items := map[int]int{}
+		errs := map[int]error{}
+		for result := range storage.ParallelScan(ctx, 3) {
+			if result.Err != nil {
+				errs[result.Segment] = result.Err
+				require.ErrorIs(t, result.Decode(&TestEntity{}), dynamorm.ErrIndexOutOfRange)
+				continue
+			}
+			items[result.Segment]++
+		}
+
+		require.Equal(t, map[int]int{0: 2, 1: 1}, items)
+		require.Len(t, errs, 2)
+		require.ErrorIs(t, errs[1], dynamorm.ErrClient)
+		require.ErrorIs(t, errs[2], dynamorm.ErrClient)
+	})
+
+	t.Run("should stop on context cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		dynamo := NewMockDynamoDB(ctrl)
+		storage := dynamorm.NewStorage("TestTable", dynamo)
+
+		dynamo.EXPECT().
+			Scan(gomock.Any(), gomock.Any()).
+			DoAndReturn(scan).
+			MinTimes(1)
+
+		results := storage.ParallelScan(ctx, 4)
+		<-results
+		cancel()
+
+		// Drain until closed
+		for range results {
+		}
+	})
+
+	t.Run("should close channel when canceled part-way", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		dynamo := NewMockDynamoDB(ctrl)
+		storage := dynamorm.NewStorage("TestTable", dynamo)
+
+		// Every segment has endless pages, even once the context is canceled
+		dynamo.EXPECT().
+			Scan(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+				return &dynamodb.ScanOutput{
+					Items:            []map[string]types.AttributeValue{email(aws.ToInt32(input.Segment), 1), email(aws.ToInt32(input.Segment), 2)},
+					LastEvaluatedKey: map[string]types.AttributeValue{"PK": &types.AttributeValueMemberS{Value: "PK"}},
+				}, nil
+			}).
+			MinTimes(1)
+
+		results := storage.ParallelScan(ctx, 2)
+		result := <-results
+		require.NoError(t, result.Err)
+		cancel()
+
+		// Only the results already buffered or in flight are left to receive
+		timeout := time.After(time.Second)
+		for {
+			select {
+			case _, ok := <-results:
+				if !ok {
+					return
+				}
+			case <-timeout:
+				require.FailNow(t, "channel not closed after cancellation")
+			}
+		}
+	})
+
+	t.Run("should return decode error", func(t *testing.T) {
+		decoder := NewMockDecoderInterface(ctrl)
+		decoder.EXPECT().Decode(gomock.Any(), gomock.Any()).Return(assert.AnError).Times(2)
+		storage := dynamorm.NewStorage("TestTable", dynamo, dynamorm.WithDecoder(decoder))
+
+		dynamo.EXPECT().Scan(ctx, gomock.Any()).DoAndReturn(scan).Times(2)
+
+		for result := range storage.ParallelScan(ctx, 1) {
+			require.ErrorIs(t, result.Decode(&TestEntity{}), dynamorm.ErrEntityDecode)
+		}
+	})
+}
+
 func TestStorageGSI1(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	t.Cleanup(ctrl.Finish)
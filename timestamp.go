@@ -0,0 +1,57 @@
+package dynamorm
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+)
+
+// CreatedAtAttribute and UpdatedAtAttribute are the attributes holding the timestamps
+// of Timestamped entities.
+const (
+	CreatedAtAttribute = "CreatedAt"
+	UpdatedAtAttribute = "UpdatedAt"
+)
+
+// Timestamped is an optional interface for entities whose creation and last update times
+// are managed by the storage, using the clock configured with WithClock.
+// The timestamps are expected to be encoded under CreatedAtAttribute and UpdatedAtAttribute.
+//
+// Save, BatchSave and Transaction.AddSave set UpdatedAt, and CreatedAt unless already set,
+// before encoding the entity. Update and Transaction.AddUpdate add
+// SET UpdatedAt = :now, CreatedAt = if_not_exists(CreatedAt, :now) to the update.
+type Timestamped interface {
+	Timestamps() (createdAt, updatedAt time.Time)
+	SetTimestamps(createdAt, updatedAt time.Time)
+}
+
+// touch sets the timestamps of a Timestamped entity about to be saved.
+func touch(e Entity, now time.Time) {
+	if t, ok := Unwrap(e).(Timestamped); ok {
+		createdAt, _ := t.Timestamps()
+		if createdAt.IsZero() {
+			createdAt = now
+		}
+		t.SetTimestamps(createdAt, now)
+	}
+}
+
+// touchUpdate adds the timestamps of a Timestamped entity to an update.
+func touchUpdate(e Entity, update expression.UpdateBuilder, now time.Time) expression.UpdateBuilder {
+	if _, ok := Unwrap(e).(Timestamped); !ok {
+		return update
+	}
+
+	createdAt := expression.Name(CreatedAtAttribute)
+	return update.
+		Set(expression.Name(UpdatedAtAttribute), expression.Value(now)).
+		Set(createdAt, createdAt.IfNotExists(expression.Value(now)))
+}
+
+// touched sets the update timestamp of a Timestamped entity once updated.
+func touched(e Entity, now time.Time) {
+	if t, ok := Unwrap(e).(Timestamped); ok {
+		createdAt, _ := t.Timestamps()
+		t.SetTimestamps(createdAt, now)
+	}
+}
@@ -0,0 +1,121 @@
+package dynamorm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Tracked is an optional interface for entities whose changes are tracked, so that
+// Storage.SaveChanges only writes the attributes that changed since the entity was loaded.
+//
+// Get, BatchGet, Query.Decode, Query.First, Query.Last, ScanResult.Decode, Save, SaveChanges,
+// and Update with ALL_NEW return values keep a snapshot of the item they read or wrote.
+// Snapshots are shared and must not be modified. Embed Tracking to implement the interface.
+type Tracked interface {
+	Snapshot() map[string]types.AttributeValue
+	SetSnapshot(map[string]types.AttributeValue)
+}
+
+// Tracking implements Tracked and is meant to be embedded into entities.
+// Its snapshot is not encoded.
+type Tracking struct {
+	snapshot map[string]types.AttributeValue
+}
+
+// Snapshot returns the item the entity was last loaded from or saved as, or nil.
+func (t *Tracking) Snapshot() map[string]types.AttributeValue {
+	return t.snapshot
+}
+
+// SetSnapshot sets the item the entity was last loaded from or saved as.
+func (t *Tracking) SetSnapshot(item map[string]types.AttributeValue) {
+	t.snapshot = item
+}
+
+// decodeItem decodes an item, without its key attributes, into an entity, keeps a snapshot of it
+// for Tracked entities, and calls the AfterLoad() hook.
+func decodeItem(decoder DecoderInterface, keys KeySchema, item map[string]types.AttributeValue, e Entity) error {
+	if err := decoder.Decode(keys.withoutKeys(item), Unwrap(e)); err != nil {
+		return fmt.Errorf("%w: %v", ErrEntityDecode, err)
+	}
+
+	snapshot(e, item)
+	return afterLoad(e)
+}
+
+// snapshot keeps a snapshot of an item for Tracked entities.
+func snapshot(e Entity, item map[string]types.AttributeValue) {
+	if t, ok := Unwrap(e).(Tracked); ok {
+		t.SetSnapshot(item)
+	}
+}
+
+// diffUpdate builds the update turning the snapshot into the item: changed and new attributes are set,
+// missing ones are removed. The key attributes and the attributes managed by the storage, such as
+// the version or the soft delete attribute, are left out. Returns false if nothing changed.
+func diffUpdate(e Entity, keys KeySchema, deletedAt string, snapshot, item map[string]types.AttributeValue) (expression.UpdateBuilder, bool) {
+	managed := map[string]bool{keys.PK: true, keys.SK: true}
+	if deletedAt != "" {
+		managed[deletedAt] = true
+	}
+	if _, ok := Unwrap(e).(Versioned); ok {
+		managed[VersionAttribute] = true
+	}
+	if _, ok := Unwrap(e).(Timestamped); ok {
+		managed[CreatedAtAttribute] = true
+		managed[UpdatedAtAttribute] = true
+	}
+
+	var update expression.UpdateBuilder
+	changed := false
+	for name, v := range item {
+		if !managed[name] && !reflect.DeepEqual(snapshot[name], v) {
+			update = update.Set(expression.Name(name), expression.Value(rawValue{v}))
+			changed = true
+		}
+	}
+	for name := range snapshot {
+		if _, ok := item[name]; !ok && !managed[name] {
+			update = update.Remove(expression.Name(name))
+			changed = true
+		}
+	}
+
+	return update, changed
+}
+
+// SaveChanges saves a Tracked entity by writing only the attributes that changed since the entity
+// was loaded or saved, using an UpdateItem request with SET and REMOVE actions instead of rewriting
+// the whole item, so that concurrent writes to other attributes are preserved. Like Save, it calls
+// BeforeSave() and recomputes the GSI keys and the expiry of the entity, and like Update, it manages
+// its version and timestamps. No request is sent when nothing changed.
+//
+// Entities that are not Tracked or have no snapshot are saved with Save. Returns ErrEntityNotFound
+// if the item was removed in the meantime. Note that a snapshot of a projection lacks the attributes
+// left out, which are then all written.
+func (s *Storage) SaveChanges(ctx context.Context, e Entity) error {
+	t, ok := Unwrap(e).(Tracked)
+	if !ok || t.Snapshot() == nil {
+		return s.Save(ctx, e)
+	}
+
+	item, err := encodeItem(e, s.encoder, s.keys, s.registry, s.ttl, nil)
+	if err != nil {
+		return err
+	}
+
+	update, changed := diffUpdate(e, s.keys, s.deletedAt, t.Snapshot(), item)
+	if !changed {
+		return nil
+	}
+
+	if err = s.updateExisting(ctx, e, update, UpdateReturnValues(ALL_NEW)); err != nil {
+		return err
+	}
+
+	return afterSave(e)
+}
@@ -2,7 +2,9 @@ package dynamorm
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
@@ -21,6 +23,8 @@ type TransactionInterface interface {
 	AddSave(Entity, ...SaveOption) error
 	// AddUpdate adds an Update operation for the given entity to the transaction.
 	AddUpdate(Entity, expression.UpdateBuilder, ...UpdateOption) error
+	// AddPatch adds an Update operation writing only the named attributes of the entity to the transaction.
+	AddPatch(Entity, ...string) error
 	// AddRemove adds a Delete operation for the given entity to the transaction.
 	AddRemove(Entity, ...RemoveOption) error
 	// AddConditionCheck adds a ConditionCheck operation for the given entity with the provided condition.
@@ -35,6 +39,17 @@ type Transaction struct {
 	encoder    EncoderInterface
 	newBuilder CreateBuilder
 	items      []types.TransactWriteItem
+
+	locks     map[int]*versionLock
+	committed []func()
+	after     []func() error
+	clock     func() time.Time
+	keys      KeySchema
+	registry  *Registry
+	ttl       string
+
+	capacityMode types.ReturnConsumedCapacity
+	capacity     *ConsumedCapacity
 }
 
 // NewTransaction creates a new Transaction with the provided DynamoDB client.
@@ -50,10 +65,18 @@ func NewTransaction(table string, client DynamoDB, encoder EncoderInterface, new
 		client:     client,
 		encoder:    encoder,
 		newBuilder: newBuilder,
+		clock:      time.Now,
+		keys:       DefaultKeySchema(),
 	}
 }
 
-func (tx *Transaction) addItem(item types.TransactWriteItem) {
+func (tx *Transaction) addItem(item types.TransactWriteItem, lock *versionLock) {
+	if lock != nil {
+		if tx.locks == nil {
+			tx.locks = make(map[int]*versionLock)
+		}
+		tx.locks[len(tx.items)] = lock
+	}
 	tx.items = append(tx.items, item)
 }
 
@@ -62,48 +85,47 @@ func (tx *Transaction) Execute(ctx context.Context) error {
 		return nil
 	}
 
-	_, err := tx.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
-		TransactItems: tx.items,
+	output, err := tx.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems:          tx.items,
+		ReturnConsumedCapacity: tx.capacityMode,
 	})
 	if err != nil {
-		return NewClientError(err)
+		return tx.clientError(err)
 	}
-	return nil
-}
+	recordCapacity(ctx, tx.capacity, output.ConsumedCapacity...)
 
-func (tx *Transaction) AddSave(e Entity, opts ...SaveOption) error {
-	if err := e.BeforeSave(); err != nil {
-		return fmt.Errorf("%w: %v", ErrEntityBeforeSave, err)
+	for _, commit := range tx.committed {
+		commit()
 	}
 
-	pk, sk := e.PkSk()
-	if pk == "" {
-		return ErrEntityPkNotSet
-	}
-	if sk == "" {
-		return ErrEntitySkNotSet
+	var errs []error
+	for _, after := range tx.after {
+		if err = after(); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	item, err := tx.encoder.Encode(e)
-	if err != nil {
-		return fmt.Errorf("%w: %v", ErrEntityEncode, err)
-	}
-	item["PK"] = &types.AttributeValueMemberS{Value: pk}
-	item["SK"] = &types.AttributeValueMemberS{Value: sk}
+	return errors.Join(errs...)
+}
 
-	pk, sk = e.GSI1()
-	if pk != "" {
-		item["GSI1PK"] = &types.AttributeValueMemberS{Value: pk}
-		if sk != "" {
-			item["GSI1SK"] = &types.AttributeValueMemberS{Value: sk}
+// clientError wraps an error returned by the client, as ErrVersionConflict when the
+// transaction was canceled because of the version condition of one of its items.
+func (tx *Transaction) clientError(err error) error {
+	var tce *types.TransactionCanceledException
+	if errors.As(err, &tce) {
+		for i, reason := range tce.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" && tx.locks[i].conflict(reason.Item) {
+				return fmt.Errorf("%w: %v", ErrVersionConflict, err)
+			}
 		}
 	}
-	pk, sk = e.GSI2()
-	if pk != "" {
-		item["GSI2PK"] = &types.AttributeValueMemberS{Value: pk}
-		if sk != "" {
-			item["GSI2SK"] = &types.AttributeValueMemberS{Value: sk}
-		}
+	return NewClientError(err)
+}
+
+func (tx *Transaction) AddSave(e Entity, opts ...SaveOption) error {
+	item, err := encodeItem(e, tx.encoder, tx.keys, tx.registry, tx.ttl, tx.clock)
+	if err != nil {
+		return err
 	}
 
 	input := &types.Put{
@@ -113,9 +135,17 @@ func (tx *Transaction) AddSave(e Entity, opts ...SaveOption) error {
 
 	builder := tx.newBuilder()
 	var nextBuilder BuilderInterface
+
+	lock := lockVersion(e, true, tx.keys.PK)
+	if lock.conditional() {
+		item[VersionAttribute] = lock.next()
+		input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+		nextBuilder = builder.WithCondition(*lock.condition)
+	}
+
 	for _, apply := range opts {
 		if apply != nil {
-			if b := apply(&dynamodb.PutItemInput{}, builder); b != nil {
+			if b := apply(&dynamodb.PutItemInput{}, lock.wrap(builder)); b != nil {
 				nextBuilder = b
 			}
 		}
@@ -130,23 +160,37 @@ func (tx *Transaction) AddSave(e Entity, opts ...SaveOption) error {
 		input.ExpressionAttributeValues = expr.Values()
 	}
 
-	tx.addItem(types.TransactWriteItem{Put: input})
+	tx.addItem(types.TransactWriteItem{Put: input}, lock)
+	tx.committed = append(tx.committed, lock.commit)
+	tx.after = append(tx.after, func() error { return afterSave(e) })
 	return nil
 }
 
 func (tx *Transaction) AddUpdate(e Entity, update expression.UpdateBuilder, opts ...UpdateOption) error {
-	pk, sk := e.PkSk()
-	if pk == "" {
-		return ErrEntityPkNotSet
+	pk, sk, err := tx.keys.entityKey(e)
+	if err != nil {
+		return err
 	}
-	if sk == "" {
-		return ErrEntitySkNotSet
+	if err = beforeUpdate(e); err != nil {
+		return err
+	}
+
+	now := tx.clock()
+	update = touchUpdate(e, update, now)
+
+	lock := lockVersion(e, false, tx.keys.PK)
+	if lock.conditional() {
+		update = update.Add(expression.Name(VersionAttribute), expression.Value(1))
 	}
 
 	builder := tx.newBuilder().WithUpdate(update)
+	if lock.conditional() {
+		builder = builder.WithCondition(*lock.condition)
+	}
+
 	for _, apply := range opts {
 		if apply != nil {
-			if b := apply(&dynamodb.UpdateItemInput{}, builder); b != nil {
+			if b := apply(&dynamodb.UpdateItemInput{}, lock.wrap(builder)); b != nil {
 				builder = b
 			}
 		}
@@ -157,43 +201,48 @@ func (tx *Transaction) AddUpdate(e Entity, update expression.UpdateBuilder, opts
 	}
 
 	input := &types.Update{
-		TableName: aws.String(tx.table),
-		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: pk},
-			"SK": &types.AttributeValueMemberS{Value: sk},
-		},
+		TableName:                 aws.String(tx.table),
+		Key:                       tx.keys.key(pk, sk),
 		UpdateExpression:          expr.Update(),
 		ConditionExpression:       expr.Condition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 	}
+	if lock.conditional() {
+		input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+	}
 
-	tx.addItem(types.TransactWriteItem{Update: input})
+	tx.addItem(types.TransactWriteItem{Update: input}, lock)
+	tx.committed = append(tx.committed, lock.commit, func() { touched(e, now) })
 	return nil
 }
 
 func (tx *Transaction) AddRemove(e Entity, opts ...RemoveOption) error {
-	pk, sk := e.PkSk()
-	if pk == "" {
-		return ErrEntityPkNotSet
+	pk, sk, err := tx.keys.entityKey(e)
+	if err != nil {
+		return err
 	}
-	if sk == "" {
-		return ErrEntitySkNotSet
+	if err = beforeRemove(e); err != nil {
+		return err
 	}
 
 	input := &types.Delete{
 		TableName: aws.String(tx.table),
-		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: pk},
-			"SK": &types.AttributeValueMemberS{Value: sk},
-		},
+		Key:       tx.keys.key(pk, sk),
 	}
 
 	builder := tx.newBuilder()
 	var nextBuilder BuilderInterface
+
+	lock := lockVersion(e, false, tx.keys.PK)
+	if lock.conditional() {
+		input.ReturnValuesOnConditionCheckFailure = types.ReturnValuesOnConditionCheckFailureAllOld
+		nextBuilder = builder.WithCondition(*lock.condition)
+	}
+
 	for _, apply := range opts {
 		if apply != nil {
-			if b := apply(&dynamodb.DeleteItemInput{}, builder); b != nil {
+			if b := apply(&dynamodb.DeleteItemInput{}, lock.wrap(builder)); b != nil {
 				nextBuilder = b
 			}
 		}
@@ -208,17 +257,15 @@ func (tx *Transaction) AddRemove(e Entity, opts ...RemoveOption) error {
 		input.ExpressionAttributeValues = expr.Values()
 	}
 
-	tx.addItem(types.TransactWriteItem{Delete: input})
+	tx.addItem(types.TransactWriteItem{Delete: input}, lock)
+	tx.after = append(tx.after, func() error { return afterRemove(e) })
 	return nil
 }
 
 func (tx *Transaction) AddConditionCheck(e Entity, cond expression.ConditionBuilder) error {
-	pk, sk := e.PkSk()
-	if pk == "" {
-		return ErrEntityPkNotSet
-	}
-	if sk == "" {
-		return ErrEntitySkNotSet
+	pk, sk, err := tx.keys.entityKey(e)
+	if err != nil {
+		return err
 	}
 
 	builder := tx.newBuilder().WithCondition(cond)
@@ -228,16 +275,13 @@ func (tx *Transaction) AddConditionCheck(e Entity, cond expression.ConditionBuil
 	}
 
 	input := &types.ConditionCheck{
-		TableName: aws.String(tx.table),
-		Key: map[string]types.AttributeValue{
-			"PK": &types.AttributeValueMemberS{Value: pk},
-			"SK": &types.AttributeValueMemberS{Value: sk},
-		},
+		TableName:                 aws.String(tx.table),
+		Key:                       tx.keys.key(pk, sk),
 		ConditionExpression:       expr.Condition(),
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 	}
 
-	tx.addItem(types.TransactWriteItem{ConditionCheck: input})
+	tx.addItem(types.TransactWriteItem{ConditionCheck: input}, nil)
 	return nil
 }
@@ -357,3 +357,110 @@ func TestTransactionAddConditionCheck(t *testing.T) {
 		require.ErrorIs(t, err, assert.AnError)
 	})
 }
+
+func TestTransactionVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	t.Cleanup(ctrl.Finish)
+
+	dynamo := NewMockDynamoDB(ctrl)
+	ctx := context.TODO()
+
+	allOld := types.ReturnValuesOnConditionCheckFailureAllOld
+
+	newTx := func(t *testing.T, save, up, del *VersionedEntity) dynamorm.TransactionInterface {
+		tx := dynamorm.NewTransaction("TestTable", dynamo, nil, nil)
+		require.NoError(t, tx.AddSave(save))
+		require.NoError(t, tx.AddUpdate(up, expression.Set(expression.Name("Name"), expression.Value("John"))))
+		require.NoError(t, tx.AddRemove(del))
+		return tx
+	}
+
+	t.Run("should execute transaction with expected versions", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				require.Len(t, input.TransactItems, 3)
+
+				put := input.TransactItems[0].Put
+				require.Equal(t, "attribute_not_exists (#0)", aws.ToString(put.ConditionExpression))
+				require.Equal(t, &types.AttributeValueMemberN{Value: "1"}, put.Item["Version"])
+				require.Equal(t, allOld, put.ReturnValuesOnConditionCheckFailure)
+
+				up := input.TransactItems[1].Update
+				require.Contains(t, aws.ToString(up.UpdateExpression), "ADD")
+				require.NotNil(t, up.ConditionExpression)
+				require.Equal(t, allOld, up.ReturnValuesOnConditionCheckFailure)
+
+				del := input.TransactItems[2].Delete
+				require.Equal(t, "#0 = :0", aws.ToString(del.ConditionExpression))
+				require.Equal(t, map[string]types.AttributeValue{
+					":0": &types.AttributeValueMemberN{Value: "7"},
+				}, del.ExpressionAttributeValues)
+				require.Equal(t, allOld, del.ReturnValuesOnConditionCheckFailure)
+
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+
+		save, up, del := &VersionedEntity{ID: "1"}, &VersionedEntity{ID: "2", Ver: 4}, &VersionedEntity{ID: "3", Ver: 7}
+		require.NoError(t, newTx(t, save, up, del).Execute(ctx))
+		require.Equal(t, int64(1), save.Ver)
+		require.Equal(t, int64(5), up.Ver)
+		require.Equal(t, int64(7), del.Ver)
+	})
+
+	t.Run("should return ErrVersionConflict", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			Return(nil, &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("None")},
+					{Code: aws.String("ConditionalCheckFailed"), Item: map[string]types.AttributeValue{
+						"Version": &types.AttributeValueMemberN{Value: "5"},
+					}},
+					{Code: aws.String("None")},
+				},
+			})
+
+		save, up, del := &VersionedEntity{ID: "1"}, &VersionedEntity{ID: "2", Ver: 4}, &VersionedEntity{ID: "3", Ver: 7}
+		err := newTx(t, save, up, del).Execute(ctx)
+		require.ErrorIs(t, err, dynamorm.ErrVersionConflict)
+		require.Equal(t, int64(0), save.Ver)
+		require.Equal(t, int64(4), up.Ver)
+	})
+
+	t.Run("should not bump version of update without version", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+				up := input.TransactItems[1].Update
+				require.NotContains(t, aws.ToString(up.UpdateExpression), "ADD")
+				require.Nil(t, up.ConditionExpression)
+				require.Empty(t, up.ReturnValuesOnConditionCheckFailure)
+				return &dynamodb.TransactWriteItemsOutput{}, nil
+			})
+
+		up := &VersionedEntity{ID: "2"}
+		require.NoError(t, newTx(t, &VersionedEntity{ID: "1"}, up, &VersionedEntity{ID: "3"}).Execute(ctx))
+		require.Equal(t, int64(0), up.Ver)
+	})
+
+	t.Run("should return client error when other item fails", func(t *testing.T) {
+		dynamo.EXPECT().
+			TransactWriteItems(ctx, gomock.Any()).
+			Return(nil, &types.TransactionCanceledException{
+				CancellationReasons: []types.CancellationReason{
+					{Code: aws.String("None")},
+					{Code: aws.String("None")},
+					{Code: aws.String("None")},
+					{Code: aws.String("ConditionalCheckFailed")},
+				},
+			})
+
+		tx := newTx(t, &VersionedEntity{ID: "1"}, &VersionedEntity{ID: "2"}, &VersionedEntity{ID: "3"})
+		require.NoError(t, tx.AddConditionCheck(&VersionedEntity{ID: "4"}, expression.AttributeExists(expression.Name("Name"))))
+
+		err := tx.Execute(ctx)
+		require.ErrorIs(t, err, dynamorm.ErrClient)
+		require.NotErrorIs(t, err, dynamorm.ErrVersionConflict)
+	})
+}
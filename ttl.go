@@ -0,0 +1,81 @@
+package dynamorm
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TTLAttribute is the default attribute holding the expiry time of Expirable entities
+// once Time to Live is enabled with WithTTL.
+const TTLAttribute = "TTL"
+
+// Expirable is an optional interface for entities that expire.
+// When Time to Live is enabled with WithTTL, Save, BatchSave and Transaction.AddSave write
+// ExpiresAt() in epoch seconds under the TTL attribute, unless it returns the zero time.
+//
+// DynamoDB deletes expired items lazily, typically within a few days, so until then
+// Get and BatchGet return ErrEntityNotFound for an expired item, and Query, Scan and their
+// counts leave it out with a filter on the TTL attribute.
+type Expirable interface {
+	ExpiresAt() time.Time
+}
+
+// expiry adds the TTL attribute of an Expirable entity to its item, unless attribute is empty.
+func expiry(e Entity, item map[string]types.AttributeValue, attribute string) {
+	if attribute == "" {
+		return
+	}
+	if x, ok := Unwrap(e).(Expirable); ok {
+		if at := x.ExpiresAt(); !at.IsZero() {
+			item[attribute] = &types.AttributeValueMemberN{Value: strconv.FormatInt(at.Unix(), 10)}
+		}
+	}
+}
+
+// expired reports whether the TTL attribute of an item is at or before now.
+// Items never expire when attribute is empty.
+func expired(item map[string]types.AttributeValue, attribute string, now time.Time) bool {
+	if attribute == "" {
+		return false
+	}
+	v, ok := item[attribute].(*types.AttributeValueMemberN)
+	if !ok {
+		return false
+	}
+
+	ttl, err := strconv.ParseInt(v.Value, 10, 64)
+	return err == nil && ttl <= now.Unix()
+}
+
+// notExpired returns the filter condition leaving out the items whose TTL attribute is at or before now.
+func notExpired(attribute string, now time.Time) expression.ConditionBuilder {
+	ttl := expression.Name(attribute)
+	return ttl.AttributeNotExists().Or(ttl.GreaterThan(expression.Value(now.Unix())))
+}
+
+// EnableTTL enables Time to Live on the table, using the attribute given to WithTTL as the expiry attribute.
+// Returns ErrTTLDisabled if the storage was created without WithTTL.
+func (s *Storage) EnableTTL(ctx context.Context) error {
+	if s.ttl == "" {
+		return ErrTTLDisabled
+	}
+
+	_, err := s.client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(s.table),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(s.ttl),
+			Enabled:       aws.Bool(true),
+		},
+	})
+	if err != nil {
+		return NewClientError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package dynamorm
+
+import (
+	"errors"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationTag is the struct tag declaring the validation rules of a field, separated by commas:
+//
+//   - required: the field must not be the zero value
+//   - min=N, max=N: the length of a string (in characters), slice or map, or the value of a number,
+//     must be at least or at most N
+//   - email: the string must be an email address
+//
+// Rules other than required are skipped for zero values. Unknown rules are reported as errors of the field,
+// except for the key template options of Keyed (pk, sk, gsi1pk, gsi1sk, gsi2pk and gsi2sk).
+//
+// Example:
+//
+//	type User struct {
+//	    Email string `dynamorm:"required,max=256,email"`
+//	}
+const ValidationTag = Tag
+
+// rules are the validation rules of ValidationTag.
+var rules = []string{"required", "min", "max", "email"}
+
+// Validator is an optional interface for entities to validate themselves, on top of the rules
+// declared with ValidationTag. The fields of a returned ValidationError are kept as is,
+// while other errors are reported as errors of the whole entity.
+type Validator interface {
+	Validate() error
+}
+
+// validate checks an entity against the rules of its fields and its Validate() method,
+// returning a ValidationError listing every problem found.
+func validate(e Entity) error {
+	verr := &ValidationError{}
+
+	v := reflect.ValueOf(Unwrap(e))
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		validateStruct(v, verr)
+	}
+
+	if validator, ok := Unwrap(e).(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			var fields *ValidationError
+			if errors.As(err, &fields) {
+				verr.Fields = append(verr.Fields, fields.Fields...)
+			} else {
+				verr.Fields = append(verr.Fields, FieldError{Message: err.Error()})
+			}
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// validateStruct checks the fields of a struct, including the ones of its embedded structs.
+func validateStruct(v reflect.Value, verr *ValidationError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		value := v.Field(i)
+		if field.Anonymous && value.Kind() == reflect.Struct {
+			validateStruct(value, verr)
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup(Tag)
+		if !ok {
+			continue
+		}
+		for _, rule := range strings.Split(tag, ",") {
+			if msg := check(value, strings.TrimSpace(rule)); msg != "" {
+				verr.Fields = append(verr.Fields, FieldError{Field: field.Name, Rule: rule, Message: msg})
+			}
+		}
+	}
+}
+
+// check returns the problem of a value breaking a rule, or an empty string.
+func check(v reflect.Value, rule string) string {
+	name, arg, _ := strings.Cut(rule, "=")
+
+	switch {
+	case name == "" || slices.Contains(keyOptions, name):
+		return ""
+	case !slices.Contains(rules, name):
+		return "has an unknown rule " + rule
+	case name == "required":
+		if v.IsZero() {
+			return "is required"
+		}
+		return ""
+	}
+	if v.IsZero() {
+		return ""
+	}
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return "has an invalid rule " + rule
+		}
+		size, ok := measure(v)
+		if !ok {
+			return "does not support rule " + rule
+		}
+		if name == "min" && size < limit {
+			return "must be at least " + arg
+		}
+		if name == "max" && size > limit {
+			return "must be at most " + arg
+		}
+	case "email":
+		if v.Kind() != reflect.String {
+			return "does not support rule " + rule
+		}
+		if addr, err := mail.ParseAddress(v.String()); err != nil || addr.Address != v.String() {
+			return "must be an email address"
+		}
+	}
+	return ""
+}
+
+// measure returns the length of a string, slice or map, or the value of a number.
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,119 @@
+package dynamorm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// VersionAttribute is the attribute holding the version of Versioned entities.
+const VersionAttribute = "Version"
+
+// Versioned is an optional interface for entities using optimistic locking.
+// The version is stored in the VersionAttribute attribute; the entity is expected to
+// expose it through a field encoded under that name, e.g. `dynamodbav:"Version"`.
+//
+// Save, Update and Remove (and their Transaction counterparts) only succeed if the stored
+// version still matches Version(), and return ErrVersionConflict otherwise. A zero version
+// means the entity has never been saved: Save then requires the item not to exist, while
+// Update and Remove are unconditional and Update leaves the stored version unchanged, so that
+// it never drifts from the version of the entity. Otherwise Save and Update increment the stored
+// version and call SetVersion with the new version once the write succeeded.
+// BatchSave and BatchRemove do not support conditions and bypass the lock.
+type Versioned interface {
+	Version() int64
+	SetVersion(int64)
+}
+
+// versionLock holds the optimistic lock of a Versioned entity for a single write.
+// A nil *versionLock is valid and stands for an entity that is not versioned.
+type versionLock struct {
+	entity    Versioned
+	expected  int64
+	condition *expression.ConditionBuilder
+}
+
+// lockVersion returns the lock of e, or nil if e is not Versioned. A condition on the
+// stored version is set when the entity has a version, or on save to require a new item,
+// i.e. one without the partition key attribute pkName.
+func lockVersion(e Entity, save bool, pkName string) *versionLock {
+	v, ok := Unwrap(e).(Versioned)
+	if !ok {
+		return nil
+	}
+
+	lock := &versionLock{entity: v, expected: v.Version()}
+	switch {
+	case lock.expected > 0:
+		cond := expression.Name(VersionAttribute).Equal(expression.Value(lock.expected))
+		lock.condition = &cond
+	case save:
+		cond := expression.AttributeNotExists(expression.Name(pkName))
+		lock.condition = &cond
+	}
+	return lock
+}
+
+// conditional reports whether the write must be conditioned on the stored version.
+func (l *versionLock) conditional() bool {
+	return l != nil && l.condition != nil
+}
+
+// next returns the attribute value of the version written by a save.
+func (l *versionLock) next() types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(l.expected+1, 10)}
+}
+
+// wrap returns the builder to hand to options, so that the conditions they set
+// are combined with the version condition.
+func (l *versionLock) wrap(builder BuilderInterface) BuilderInterface {
+	if !l.conditional() {
+		return builder
+	}
+	return &versionBuilder{builder, *l.condition}
+}
+
+// commit sets the new version on the entity after a successful save or update.
+// It is a no-op when the previous version is unknown.
+func (l *versionLock) commit() {
+	if l.conditional() {
+		l.entity.SetVersion(l.expected + 1)
+	}
+}
+
+// conflict reports whether the item returned by a failed condition check, if any,
+// does not match the expected version.
+func (l *versionLock) conflict(item map[string]types.AttributeValue) bool {
+	if !l.conditional() {
+		return false
+	}
+	if l.expected == 0 {
+		return len(item) > 0
+	}
+
+	v, ok := item[VersionAttribute].(*types.AttributeValueMemberN)
+	return !ok || v.Value != strconv.FormatInt(l.expected, 10)
+}
+
+// clientError wraps an error returned by the client, as ErrVersionConflict when
+// the write was rejected because of the version condition.
+func (l *versionLock) clientError(err error) error {
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) && l.conflict(ccf.Item) {
+		return fmt.Errorf("%w: %v", ErrVersionConflict, err)
+	}
+	return NewClientError(err)
+}
+
+// versionBuilder ANDs the conditions set by options with the version condition.
+type versionBuilder struct {
+	BuilderInterface
+	condition expression.ConditionBuilder
+}
+
+func (b *versionBuilder) WithCondition(condition expression.ConditionBuilder) BuilderInterface {
+	return b.BuilderInterface.WithCondition(b.condition.And(condition))
+}